@@ -3,6 +3,8 @@ package ast
 import (
 	"b/token"
 	"bytes"
+	"strconv"
+	"strings"
 )
 
 // Node defines an interface for all nodes in the AST.
@@ -40,6 +42,7 @@ func (es *ExpressionStatement) String() string {
 type VarStatement struct {
 	Token token.Token
 	Name  *Identifier
+	Type  TypeExpr // nil when there is no annotation
 	Value Expression
 }
 
@@ -50,10 +53,13 @@ func (vs *VarStatement) String() string {
 
 	out.WriteString(vs.TokenLiteral() + " ")
 	out.WriteString(vs.Name.Value)
-	out.WriteString(" = ")
+
+	if vs.Type != nil {
+		out.WriteString(": " + vs.Type.String())
+	}
 
 	if vs.Value != nil {
-		out.WriteString(vs.Value.String())
+		out.WriteString(" = " + vs.Value.String())
 	}
 
 	out.WriteString(";")
@@ -64,6 +70,7 @@ func (vs *VarStatement) String() string {
 type ValStatement struct {
 	Token token.Token
 	Name  *Identifier
+	Type  TypeExpr // nil when there is no annotation
 	Value Expression
 }
 
@@ -74,10 +81,13 @@ func (vs *ValStatement) String() string {
 
 	out.WriteString(vs.TokenLiteral() + " ")
 	out.WriteString(vs.Name.Value)
-	out.WriteString(" = ")
+
+	if vs.Type != nil {
+		out.WriteString(": " + vs.Type.String())
+	}
 
 	if vs.Value != nil {
-		out.WriteString(vs.Value.String())
+		out.WriteString(" = " + vs.Value.String())
 	}
 
 	out.WriteString(";")
@@ -105,10 +115,10 @@ func (rs *ReturnStatement) TokenLiteral() string { return rs.Token.Literal }
 func (rs *ReturnStatement) String() string {
 	var out bytes.Buffer
 
-	out.WriteString(rs.TokenLiteral() + " ")
+	out.WriteString(rs.TokenLiteral())
 
 	if rs.ReturnValue != nil {
-		out.WriteString(rs.ReturnValue.String())
+		out.WriteString(" " + rs.ReturnValue.String())
 	}
 
 	out.WriteString(";")
@@ -126,6 +136,365 @@ func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
 
+// FloatLiteral refers to any number written with a fraction or an
+// exponent, it is held as a float64
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (fl *FloatLiteral) expressionNode()      {}
+func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
+
+// BooleanLiteral is either true or false
+type BooleanLiteral struct {
+	Token token.Token
+	Value bool
+}
+
+func (bl *BooleanLiteral) expressionNode()      {}
+func (bl *BooleanLiteral) TokenLiteral() string { return bl.Token.Literal }
+func (bl *BooleanLiteral) String() string       { return bl.Token.Literal }
+
+// StringLiteral is a string without any interpolation. Value has the
+// escapes already decoded, Raw is set for backtick strings
+type StringLiteral struct {
+	Token token.Token
+	Value string
+	Raw   bool
+}
+
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) String() string {
+	if sl.Raw {
+		return "`" + sl.Value + "`"
+	}
+	return strconv.Quote(sl.Value)
+}
+
+// CharLiteral is a single character written between single quotes
+type CharLiteral struct {
+	Token token.Token
+	Value rune
+}
+
+func (cl *CharLiteral) expressionNode()      {}
+func (cl *CharLiteral) TokenLiteral() string { return cl.Token.Literal }
+func (cl *CharLiteral) String() string       { return strconv.QuoteRune(cl.Value) }
+
+// SymbolLiteral is a :name symbol, Value holds the name without the colon
+type SymbolLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (sl *SymbolLiteral) expressionNode()      {}
+func (sl *SymbolLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *SymbolLiteral) String() string       { return ":" + sl.Value }
+
+// PrefixExpression is a unary operator applied to the expression after
+// it, ie. -5 or not done
+type PrefixExpression struct {
+	Token    token.Token // the prefix token, ie. !
+	Operator string
+	Right    Expression
+}
+
+func (pe *PrefixExpression) expressionNode()      {}
+func (pe *PrefixExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PrefixExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(pe.Operator)
+	if pe.Token.Type == token.NOT {
+		// keep the keyword apart from its operand
+		out.WriteString(" ")
+	}
+	out.WriteString(pe.Right.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// InfixExpression is a binary operator applied to the expressions on
+// either side of it, ie. 5 * 5
+type InfixExpression struct {
+	Token    token.Token // the operator token, ie. +
+	Left     Expression
+	Operator string
+	Right    Expression
+}
+
+func (ie *InfixExpression) expressionNode()      {}
+func (ie *InfixExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *InfixExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString(" " + ie.Operator + " ")
+	out.WriteString(ie.Right.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// CallExpression is a call of Function with Arguments, ie. add(1, 2)
+type CallExpression struct {
+	Token     token.Token // the ( token
+	Function  Expression  // an identifier or anything that gives a function
+	Arguments []Expression
+}
+
+func (ce *CallExpression) expressionNode()      {}
+func (ce *CallExpression) TokenLiteral() string { return ce.Token.Literal }
+func (ce *CallExpression) String() string {
+	var out bytes.Buffer
+
+	args := []string{}
+	for _, a := range ce.Arguments {
+		args = append(args, a.String())
+	}
+
+	out.WriteString(ce.Function.String())
+	out.WriteString("(")
+	out.WriteString(strings.Join(args, ", "))
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// MemberExpression is access to a field or method, ie. obj.field
+type MemberExpression struct {
+	Token    token.Token // the . token
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	return "(" + me.Object.String() + "." + me.Property.String() + ")"
+}
+
+// IndexExpression is a single element of a list or map, ie. xs[i]
+type IndexExpression struct {
+	Token token.Token // the [ token
+	Left  Expression
+	Index Expression
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ie.Left.String())
+	out.WriteString("[")
+	out.WriteString(ie.Index.String())
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// SliceExpression is xs[Low:High:Step], any of the bounds can be left out
+// and are nil then
+type SliceExpression struct {
+	Token token.Token // the [ token
+	Left  Expression
+	Low   Expression
+	High  Expression
+	Step  Expression
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Low != nil {
+		out.WriteString(se.Low.String())
+	}
+	out.WriteString(":")
+	if se.High != nil {
+		out.WriteString(se.High.String())
+	}
+	if se.Step != nil {
+		out.WriteString(":" + se.Step.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
+// BlockStatement is a list of statements between braces, ie. the body of
+// a function. A block is Open when it ends in an expression that is not
+// followed by a `;`, the block then has the value of that expression
+type BlockStatement struct {
+	Token      token.Token // the { token
+	Statements []Statement
+	Open       bool
+}
+
+func (bs *BlockStatement) statementNode()       {}
+func (bs *BlockStatement) TokenLiteral() string { return bs.Token.Literal }
+
+// Value returns the expression that gives the block its value, or nil
+// when the block is closed
+func (bs *BlockStatement) Value() Expression {
+	if !bs.Open {
+		return nil
+	}
+	return bs.Statements[len(bs.Statements)-1].(*ExpressionStatement).Expression
+}
+func (bs *BlockStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("{")
+	for _, s := range bs.Statements {
+		out.WriteString(s.String())
+	}
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// IfExpression is if/elif/else, it has the value of the block that runs.
+// Alternative is nil when there is no else
+type IfExpression struct {
+	Token       token.Token // the if token
+	Condition   Expression
+	Consequence *BlockStatement
+	Elifs       []*ElifClause
+	Alternative *BlockStatement
+}
+
+// ElifClause is one `elif condition { ... }` of an if expression, `else if`
+// is read as an elif too
+type ElifClause struct {
+	Token       token.Token // the elif token, or the if of `else if`
+	Condition   Expression
+	Consequence *BlockStatement
+}
+
+func (ie *IfExpression) expressionNode()      {}
+func (ie *IfExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IfExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("if ")
+	out.WriteString(ie.Condition.String())
+	out.WriteString(" ")
+	out.WriteString(ie.Consequence.String())
+
+	for _, elif := range ie.Elifs {
+		out.WriteString(" elif ")
+		out.WriteString(elif.Condition.String())
+		out.WriteString(" ")
+		out.WriteString(elif.Consequence.String())
+	}
+
+	if ie.Alternative != nil {
+		out.WriteString(" else ")
+		out.WriteString(ie.Alternative.String())
+	}
+
+	return out.String()
+}
+
+// Parameter is one parameter of a function or one of its named results.
+// Type is nil when the parameter has no annotation and Default is nil
+// when no default value is given. A `val` parameter can not be assigned to
+type Parameter struct {
+	Token     token.Token // the val token or the name
+	Name      *Identifier
+	Type      TypeExpr
+	Variadic  bool // written as ...T, only the last parameter can be
+	Default   Expression
+	Immutable bool
+}
+
+func (pa *Parameter) String() string {
+	var out bytes.Buffer
+
+	if pa.Immutable {
+		out.WriteString("val ")
+	}
+	out.WriteString(pa.Name.String())
+	if pa.Type != nil {
+		out.WriteString(": ")
+		if pa.Variadic {
+			out.WriteString("...")
+		}
+		out.WriteString(pa.Type.String())
+	}
+	if pa.Default != nil {
+		out.WriteString(" = " + pa.Default.String())
+	}
+
+	return out.String()
+}
+
+// FunctionLiteral is a function written with fun. Name is nil for an
+// anonymous function, the result is either a single Result type or a list
+// of NamedResults
+type FunctionLiteral struct {
+	Token        token.Token // the fun token
+	Name         *Identifier
+	Parameters   []*Parameter
+	Result       TypeExpr
+	NamedResults []*Parameter
+	Body         *BlockStatement
+}
+
+func (fl *FunctionLiteral) expressionNode()      {}
+func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
+func (fl *FunctionLiteral) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(fl.TokenLiteral())
+	if fl.Name != nil {
+		out.WriteString(" " + fl.Name.String())
+	}
+	out.WriteString("(" + joinParameters(fl.Parameters) + ")")
+	if fl.Result != nil {
+		out.WriteString(" " + fl.Result.String())
+	}
+	if fl.NamedResults != nil {
+		out.WriteString(" (" + joinParameters(fl.NamedResults) + ")")
+	}
+	out.WriteString(" " + fl.Body.String())
+
+	return out.String()
+}
+
+func joinParameters(params []*Parameter) string {
+	strs := []string{}
+	for _, p := range params {
+		strs = append(strs, p.String())
+	}
+	return strings.Join(strs, ", ")
+}
+
+// FunctionStatement is a named function declared on its own, ie.
+// fun main() { ... }
+type FunctionStatement struct {
+	Token    token.Token // the fun token
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (fs *FunctionStatement) statementNode()       {}
+func (fs *FunctionStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *FunctionStatement) String() string       { return fs.Function.String() }
+
 type Program struct {
 	Statements []Statement
 }
@@ -0,0 +1,156 @@
+package ast
+
+import (
+	"b/token"
+	"bytes"
+	"strings"
+)
+
+// TypeExpr defines the interface for all nodes that describe a type, ie.
+// the int in `var x: int = 5`
+type TypeExpr interface {
+	Node
+	typeNode()
+}
+
+// NamedType is a type referred to by name, either a builtin like int or
+// str or one declared with `type`
+type NamedType struct {
+	Token token.Token
+	Name  string
+}
+
+func (nt *NamedType) typeNode()            {}
+func (nt *NamedType) TokenLiteral() string { return nt.Token.Literal }
+func (nt *NamedType) String() string       { return nt.Name }
+
+// ListType is list[Elem]
+type ListType struct {
+	Token token.Token // the list token
+	Elem  TypeExpr
+}
+
+func (lt *ListType) typeNode()            {}
+func (lt *ListType) TokenLiteral() string { return lt.Token.Literal }
+func (lt *ListType) String() string       { return "list[" + lt.Elem.String() + "]" }
+
+// MapType is map[Key]Value, dict[Key]Value means the same thing
+type MapType struct {
+	Token token.Token // the map or dict token
+	Key   TypeExpr
+	Value TypeExpr
+}
+
+func (mt *MapType) typeNode()            {}
+func (mt *MapType) TokenLiteral() string { return mt.Token.Literal }
+func (mt *MapType) String() string {
+	return mt.Token.Literal + "[" + mt.Key.String() + "]" + mt.Value.String()
+}
+
+// SetType is set[Elem]
+type SetType struct {
+	Token token.Token // the set token
+	Elem  TypeExpr
+}
+
+func (st *SetType) typeNode()            {}
+func (st *SetType) TokenLiteral() string { return st.Token.Literal }
+func (st *SetType) String() string       { return "set[" + st.Elem.String() + "]" }
+
+// ChanDir is the direction values can be passed through a channel
+type ChanDir int
+
+const (
+	ChanBoth ChanDir = iota // chan T
+	ChanSend                // chan<- T
+	ChanRecv                // <-chan T
+)
+
+// ChanType is a channel of Elem that can be limited to one direction
+type ChanType struct {
+	Token token.Token // the chan token, or <- for a receive only channel
+	Dir   ChanDir
+	Elem  TypeExpr
+}
+
+func (ct *ChanType) typeNode()            {}
+func (ct *ChanType) TokenLiteral() string { return ct.Token.Literal }
+func (ct *ChanType) String() string {
+	switch ct.Dir {
+	case ChanSend:
+		return "chan<- " + ct.Elem.String()
+	case ChanRecv:
+		return "<-chan " + ct.Elem.String()
+	}
+	return "chan " + ct.Elem.String()
+}
+
+// FuncType is the signature of a function. Variadic is set when the last
+// parameter was written as ...T, Result is nil when nothing is returned
+type FuncType struct {
+	Token    token.Token // the fun token
+	Params   []TypeExpr
+	Variadic bool
+	Result   TypeExpr
+}
+
+func (ft *FuncType) typeNode()            {}
+func (ft *FuncType) TokenLiteral() string { return ft.Token.Literal }
+func (ft *FuncType) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range ft.Params {
+		params = append(params, p.String())
+	}
+	if ft.Variadic {
+		params[len(params)-1] = "..." + params[len(params)-1]
+	}
+
+	out.WriteString("fun(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(")")
+	if ft.Result != nil {
+		out.WriteString(" " + ft.Result.String())
+	}
+
+	return out.String()
+}
+
+// UnionType is <A|B>, a value of any one of Types
+type UnionType struct {
+	Token token.Token // the < token
+	Types []TypeExpr
+}
+
+func (ut *UnionType) typeNode()            {}
+func (ut *UnionType) TokenLiteral() string { return ut.Token.Literal }
+func (ut *UnionType) String() string {
+	types := []string{}
+	for _, t := range ut.Types {
+		types = append(types, t.String())
+	}
+	return "<" + strings.Join(types, "|") + ">"
+}
+
+// ObjField is a single `name: type` entry of an obj shape
+type ObjField struct {
+	Name *Identifier
+	Type TypeExpr
+}
+
+// ObjType is obj{name: str, age: int}, the shape an object has to have
+type ObjType struct {
+	Token  token.Token // the obj token
+	Fields []*ObjField
+}
+
+func (ot *ObjType) typeNode()            {}
+func (ot *ObjType) TokenLiteral() string { return ot.Token.Literal }
+func (ot *ObjType) String() string {
+	fields := []string{}
+	for _, f := range ot.Fields {
+		fields = append(fields, f.Name.String()+": "+f.Type.String())
+	}
+	return "obj{" + strings.Join(fields, ", ") + "}"
+}
@@ -71,7 +71,7 @@ fun main() {
         # Logical operators wont have assignment combo operators
     ###
 
-    fun test(x : int, y : int) : int { return x + y }() # Call a function inline
+    fun sum(x : int, y : int) : int { return x + y }() # Call a function inline
     val mytest = fun(x : int, y : int) : int { return x + y } # Assinging variable to function
     mytest(1,4) # Calling above function
 
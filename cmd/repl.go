@@ -27,5 +27,9 @@ func Start(in io.Reader, out io.Writer) {
 		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
 			fmt.Printf("%+v\n", tok)
 		}
+
+		for _, err := range l.Errors() {
+			fmt.Printf("error: %s\n", err)
+		}
 	}
 }
\ No newline at end of file
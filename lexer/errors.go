@@ -0,0 +1,62 @@
+package lexer
+
+import (
+	"b/token"
+	"fmt"
+)
+
+// ErrorKind says what sort of problem the lexer ran into
+type ErrorKind int
+
+const (
+	UnexpectedChar      ErrorKind = iota // a char that cannot start any token
+	InvalidUTF8                          // a byte that is not valid UTF-8
+	BadEscape                            // an unknown or malformed escape sequence
+	MalformedNumber                      // a number with bad digits or separators
+	MalformedLiteral                     // a char or regex literal that is not well formed
+	UnterminatedString                   // a string, char or regex literal that is never closed
+	UnterminatedComment                  // a block comment that is never closed
+	ReadError                            // the reader failed before the end of the input
+)
+
+var errorKindNames = map[ErrorKind]string{
+	UnexpectedChar:      "unexpected character",
+	InvalidUTF8:         "invalid UTF-8",
+	BadEscape:           "bad escape",
+	MalformedNumber:     "malformed number",
+	MalformedLiteral:    "malformed literal",
+	UnterminatedString:  "unterminated string",
+	UnterminatedComment: "unterminated comment",
+	ReadError:           "read error",
+}
+
+func (k ErrorKind) String() string {
+	if name, ok := errorKindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+// Error is a problem found in the input along with where it was found
+type Error struct {
+	Pos  token.Position
+	Kind ErrorKind
+	Msg  string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// Errors returns every error the lexer has found so far in the order they
+// were found
+func (l *Lexer) Errors() []Error {
+	return l.errors
+}
+
+// report records an error and returns its message so that it can be used
+// as the literal of the ILLEGAL token being built
+func (l *Lexer) report(pos token.Position, kind ErrorKind, msg string) string {
+	l.errors = append(l.errors, Error{Pos: pos, Kind: kind, Msg: msg})
+	return msg
+}
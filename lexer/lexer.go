@@ -2,293 +2,944 @@ package lexer
 
 import (
 	"b/token"
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
+// eof is the char returned once the input has been used up
+const eof = -1
+
+// Mode is a set of flags that change which tokens the lexer emits
+type Mode uint
+
+const (
+	// ScanComments makes the lexer return COMMENT tokens instead of skipping them
+	ScanComments Mode = 1 << iota
+	// ScanTrivia makes the lexer attach the whitespace and comments around
+	// each token to it so the input can be rebuilt from the tokens
+	ScanTrivia
+)
+
+// char is a single decoded rune from the input along with the number of
+// bytes it took up. A byte that is not valid UTF-8 decodes to
+// utf8.RuneError and is kept in bad so the source text of a token is
+// never lost
+type char struct {
+	r    rune
+	size int
+	bad  byte
+}
+
 type Lexer struct {
-	input   string
-	pos     int // current position
-	nextPos int
-	ch      byte // current char under examination
+	r       *bufio.Reader
+	pos     int  // byte offset of the current char
+	nextPos int  // byte offset of the char after the current one
+	ch      rune // current char under examination
+	bad     byte // the raw byte when the current char is not valid UTF-8
+
+	ahead []char // chars that have been peeked at but not consumed
+	lit   []byte // source bytes consumed since the current token started
+
+	filename string
+	line     int // line of the current char (1-based)
+	col      int // column of the current char (1-based)
 
-	inputLen int
+	mode Mode
+
+	leading []token.Trivia // trivia skipped so far before the current token
+
+	prev  token.TokenType   // type of the last token returned, comments are not counted
+	stack []token.TokenType // the brackets and interpolations that are still open
+
+	errors  []Error
+	readErr bool // set once the reader has failed
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+// isLetter reports whether ch can start an identifier, any unicode
+// letter is allowed
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
-func isDigit(ch byte) bool {
-	return '0' <= ch && ch <= '9' || ch == '_'
+// isDigit reports whether ch can be used after the first char of an
+// identifier along with the letters
+func isDigit(ch rune) bool {
+	return isDecimal(ch) || ch >= utf8.RuneSelf && unicode.IsDigit(ch)
 }
 
 func (l *Lexer) readIdentifier() string {
-	position := l.pos
+	position := len(l.lit)
 	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
-	return l.input[position:l.pos]
+	return string(l.lit[position:])
 }
 
-func (l *Lexer) skipWhitespace() {
+// skipWhitespace consumes spaces, tabs and newlines. When stopAtNewline is
+// set it leaves the first newline in place so a SEMICOLON can be made from it
+func (l *Lexer) skipWhitespace(stopAtNewline bool) {
 	for l.ch == ' ' || l.ch == '\t' || l.ch == '\n' || l.ch == '\r' {
+		start := len(l.lit)
+		if l.ch == '\n' {
+			if stopAtNewline {
+				return
+			}
+			l.readChar()
+			l.addTrivia(token.Newline, start)
+			continue
+		}
+		l.skipSpaces()
+		l.addTrivia(token.Whitespace, start)
+	}
+}
+
+// skipSpaces consumes the whitespace up to the next newline
+func (l *Lexer) skipSpaces() {
+	for l.ch == ' ' || l.ch == '\t' || l.ch == '\r' {
 		l.readChar()
 	}
 }
 
-func (l *Lexer) readNumber() string {
-	position := l.pos
-	nextChar := l.peekChar()
+// addTrivia records the source consumed since start as leading trivia of
+// the next token when trivia is being kept
+func (l *Lexer) addTrivia(kind token.TriviaKind, start int) {
+	if l.mode&ScanTrivia != 0 && len(l.lit) > start {
+		l.leading = append(l.leading, token.Trivia{Kind: kind, Text: string(l.lit[start:])})
+	}
+}
+
+// readTrailingTrivia consumes the spaces and line comment that follow tok
+// on the same line. The newline itself is left for the next token, it is
+// either a SEMICOLON or leading trivia
+func (l *Lexer) readTrailingTrivia(tok token.Token) []token.Trivia {
+	switch tok.Type {
+	case token.EOF, token.STRINGSTART, token.STRINGMID, token.INTERPEND:
+		// nothing can come between the pieces of an interpolated string
+		return nil
+	case token.SEMICOLON:
+		if tok.Text == "\n" {
+			return nil
+		}
+	}
+	var trivia []token.Trivia
+	start := len(l.lit)
+	l.skipSpaces()
+	if len(l.lit) > start {
+		trivia = append(trivia, token.Trivia{Kind: token.Whitespace, Text: string(l.lit[start:])})
+	}
+	if l.ch == '#' && l.mode&ScanComments == 0 && !l.atBlockComment() {
+		comment, _ := l.readComment()
+		trivia = append(trivia, token.Trivia{Kind: token.Comment, Text: comment})
+	}
+	return trivia
+}
+
+// endsStatement reports whether a newline after a token of type t ends the
+// statement. That is everything that can end an operand plus the keywords
+// that make up a whole statement and the type keywords that can end an
+// annotation
+func endsStatement(t token.TokenType) bool {
+	switch t {
+	case token.RETURN, token.BREAK, token.CONTINUE, token.YIELD:
+		return true
+	}
+	return token.IsTypeKeyword(t) || endsOperand(t)
+}
+
+// insertSemicolon reports whether the next newline should be returned as a
+// SEMICOLON. No semicolons are inserted inside parens, brackets or
+// interpolations so an expression can carry on over several lines there,
+// and none are inserted after an operator or comma that needs more input
+func (l *Lexer) insertSemicolon() bool {
+	switch l.top() {
+	case token.LPAREN, token.LBRACKET, token.INTERPSTART, token.INTERPDEBUG:
+		return false
+	}
+	return endsStatement(l.prev)
+}
+
+// endsOperand reports whether a token of type t can be the last token of
+// an operand, in which case a following `/` is division rather than the
+// start of a regex
+func endsOperand(t token.TokenType) bool {
+	switch t {
+	case token.IDENT, token.INTLIT, token.FLOATLIT, token.STRINGLIT, token.CHARLIT, token.SYMBOL, token.STRINGEND, token.REGEX,
+		token.TRUE, token.FALSE, token.RPAREN, token.RBRACKET, token.RBRACE,
+		token.PLUSPLUS, token.MINUSMINUS, token.QUESTION:
+		return true
+	}
+	return false
+}
+
+// regexAllowed reports whether a `/` at the current position starts a
+// regex. That is the case in operand position, meaning after anything that
+// cannot end an operand such as an operator, `(`, `,`, a keyword or the
+// SEMICOLON put in at the end of a line. Inside brackets a line that starts
+// with `/` carries on the expression so it is division there. `//` and `/=`
+// are always operators, a pattern that starts with `=` has to be written
+// as `/\=.../`
+func (l *Lexer) regexAllowed() bool {
+	if l.peekChar() == '/' || l.peekChar() == '=' {
+		return false
+	}
+	return !endsOperand(l.prev)
+}
+
+// readRegex consumes a regex literal including its flags. The pattern is
+// returned as written except that `\/` becomes `/`, any other escape is
+// left for the regex engine. A `/` inside a character class does not end
+// the pattern. msg describes the problem when the regex is malformed
+func (l *Lexer) readRegex() (pattern string, msg string) {
+	start := l.position()
+	var out []byte
+	inClass := false
+	l.readChar()
+	for inClass || l.ch != '/' {
+		switch l.ch {
+		case '\n', eof:
+			return string(out), l.report(start, UnterminatedString, "unterminated regex literal")
+		case '\\':
+			if l.peekChar() == '/' {
+				l.readChar()
+			} else if l.peekChar() != '\n' && l.peekChar() != eof {
+				out = l.appendChar(out)
+				l.readChar()
+			}
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		}
+		out = l.appendLiteralChar(out)
+		l.readChar()
+	}
+	l.readChar()
+
+	for isLetter(l.ch) {
+		switch l.ch {
+		case 'i', 'm', 's', 'U':
+		default:
+			if msg == "" {
+				msg = l.report(l.position(), MalformedLiteral, fmt.Sprintf("unknown regex flag %q", l.ch))
+			}
+		}
+		l.readChar()
+	}
+	return string(out), msg
+}
+
+// readComment consumes a `#` line comment or a `###` block comment and
+// returns its text including the delimiters. ok is false when a block
+// comment is still open at the end of the input
+func (l *Lexer) readComment() (comment string, ok bool) {
+	position := len(l.lit)
+	if l.atBlockComment() {
+		// skipping over the opening ###
+		l.readChar()
+		l.readChar()
+		l.readChar()
+		for {
+			if l.ch == eof {
+				return string(l.lit[position:]), false
+			}
+			if l.ch == '#' && l.peekChar() == '#' && l.peekCharAtOffset(2) == '#' {
+				l.readChar()
+				l.readChar()
+				l.readChar()
+				return string(l.lit[position:]), true
+			}
+			l.readChar()
+		}
+	}
+	for l.ch != '\n' && l.ch != eof {
+		l.readChar()
+	}
+	return string(l.lit[position:]), true
+}
+
+// atBlockComment reports whether the current char starts a ### comment
+func (l *Lexer) atBlockComment() bool {
+	return l.ch == '#' && l.peekChar() == '#' && l.peekCharAtOffset(2) == '#'
+}
+
+// readNumber consumes an INTLIT or FLOATLIT. Integers may start with a
+// 0x, 0o or 0b base prefix, floats are always decimal with a fraction
+// and/or an exponent. `_` can be used to group digits as long as it sits
+// between two digits or right after a base prefix. msg describes the first
+// problem found when the literal is malformed
+func (l *Lexer) readNumber() (lit string, tokType token.TokenType, msg string) {
+	start := l.position()
+	position := len(l.lit)
+	tokType = token.INTLIT
+
+	base, prev := 10, rune(0)
 	if l.ch == '0' {
-		if nextChar == 'b' ||
-			nextChar == 'B' ||
-			nextChar == 'x' ||
-			nextChar == 'X' ||
-			nextChar == 'o' ||
-			nextChar == 'O' {
+		switch unicode.ToLower(l.peekChar()) {
+		case 'x':
+			base = 16
+		case 'o':
+			base = 8
+		case 'b':
+			base = 2
+		}
+		if base != 10 {
 			// skipping over the 2 chars
 			l.readChar()
 			l.readChar()
+			prev = 'p'
+		}
+	}
+
+	n, invalid, badSep := l.readDigits(base, prev)
+	switch {
+	case n == 0:
+		msg = fmt.Sprintf("%s literal has no digits", baseName(base))
+	case invalid != 0:
+		msg = fmt.Sprintf("invalid digit %q in %s literal", invalid, baseName(base))
+	}
+
+	if base == 10 && l.ch == '.' && isDecimal(l.peekChar()) {
+		tokType = token.FLOATLIT
+		l.readChar()
+		_, _, fracSep := l.readDigits(10, 0)
+		badSep = badSep || fracSep
+	}
+
+	if base == 10 && (l.ch == 'e' || l.ch == 'E') {
+		tokType = token.FLOATLIT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		n, _, expSep := l.readDigits(10, 0)
+		badSep = badSep || expSep
+		if n == 0 && msg == "" {
+			msg = "exponent has no digits"
+		}
+	}
+
+	if badSep && msg == "" {
+		msg = "'_' must separate successive digits"
+	}
+	if isLetter(l.ch) || isDigit(l.ch) {
+		// 123abc or 0x1p3 is one bad literal rather than a number and a name
+		if msg == "" {
+			msg = fmt.Sprintf("invalid character %q in %s literal", l.ch, baseName(base))
+		}
+		for isLetter(l.ch) || isDigit(l.ch) {
+			l.readChar()
 		}
 	}
+	if msg != "" {
+		l.report(start, MalformedNumber, msg)
+	}
+	return string(l.lit[position:]), tokType, msg
+}
+
+// readDigits consumes the digits and `_` separators of a number. Every
+// decimal digit is consumed whatever the base so that a bad one can be
+// reported, invalid is the first digit too large for base. prev is 'p'
+// when a base prefix was just read, which a `_` may follow. badSep is set
+// when a `_` is not between two digits
+func (l *Lexer) readDigits(base int, prev rune) (n int, invalid rune, badSep bool) {
 	for {
-		ok := isDigit(l.ch) ||
-			(l.ch == '.' && isDigit(l.peekChar())) ||
-			(isDigit(l.ch) && l.peekChar() == '.')
-		if !ok {
-			break
+		switch {
+		case l.ch == '_':
+			if prev != 'd' && prev != 'p' {
+				badSep = true
+			}
+			prev = '_'
+		case isDecimal(l.ch) || base == 16 && digitVal(l.ch) < 16:
+			if digitVal(l.ch) >= base && invalid == 0 {
+				invalid = l.ch
+			}
+			prev = 'd'
+			n++
+		default:
+			if prev == '_' {
+				badSep = true
+			}
+			return n, invalid, badSep
 		}
 		l.readChar()
 	}
-	return l.input[position:l.pos]
 }
 
+func isDecimal(ch rune) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+func baseName(base int) string {
+	switch base {
+	case 2:
+		return "binary"
+	case 8:
+		return "octal"
+	case 16:
+		return "hexadecimal"
+	}
+	return "decimal"
+}
+
+// NextToken returns the next token from the input, once the input is used
+// up every call returns EOF
 func (l *Lexer) NextToken() token.Token {
+	l.leading = nil
+	tok := l.readToken()
+	if l.mode&ScanTrivia != 0 {
+		tok.Leading = l.leading
+		tok.Trailing = l.readTrailingTrivia(tok)
+	}
+	l.track(tok)
+	return tok
+}
+
+// track updates the state that depends on which tokens have already been
+// returned, it never looks at the input
+func (l *Lexer) track(tok token.Token) {
+	switch tok.Type {
+	case token.COMMENT:
+		return
+	case token.LBRACE, token.LPAREN, token.LBRACKET, token.INTERPSTART, token.INTERPDEBUG:
+		l.stack = append(l.stack, tok.Type)
+	case token.RBRACE:
+		if l.top() == token.LBRACE {
+			l.stack = l.stack[:len(l.stack)-1]
+		}
+	case token.RPAREN:
+		if l.top() == token.LPAREN {
+			l.stack = l.stack[:len(l.stack)-1]
+		}
+	case token.RBRACKET:
+		if l.top() == token.LBRACKET {
+			l.stack = l.stack[:len(l.stack)-1]
+		}
+	case token.INTERPEND:
+		l.stack = l.stack[:len(l.stack)-1]
+	}
+	l.prev = tok.Type
+}
+
+// top returns the innermost open brace or interpolation
+func (l *Lexer) top() token.TokenType {
+	if len(l.stack) == 0 {
+		return ""
+	}
+	return l.stack[len(l.stack)-1]
+}
+
+func (l *Lexer) readToken() token.Token {
 	var tok token.Token
 
-	l.skipWhitespace()
+	// the pieces of an interpolated string are never separated by whitespace
+	switch l.prev {
+	case token.STRINGSTART, token.STRINGMID:
+		return l.readInterpStart()
+	case token.INTERPEND:
+		return l.readStringPart(false)
+	}
+
+	semi := l.insertSemicolon()
+	l.skipWhitespace(semi)
+	l.lit = l.lit[:0]
+	pos := l.position()
+	for l.ch == '#' {
+		comment, ok := l.readComment()
+		if !ok {
+			msg := l.report(pos, UnterminatedComment, "unterminated block comment")
+			return token.Token{Type: token.ILLEGAL, Literal: msg, Text: comment, Pos: pos}
+		}
+		if l.mode&ScanComments != 0 {
+			return token.Token{Type: token.COMMENT, Literal: comment, Text: comment, Pos: pos}
+		}
+		if l.mode&ScanTrivia != 0 {
+			l.leading = append(l.leading, token.Trivia{Kind: token.Comment, Text: comment})
+		}
+		l.skipWhitespace(semi)
+		l.lit = l.lit[:0]
+		pos = l.position()
+	}
+
+	if semi && l.ch == '\n' {
+		l.readChar()
+		return token.Token{Type: token.SEMICOLON, Literal: "\n", Text: "\n", Pos: pos}
+	}
 
 	switch l.ch {
-	case '=':
-		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			tok = token.Token{Type: token.EQ, Literal: string(ch) + string(l.ch)}
-		} else {
-			tok = newToken(token.ASSIGN, l.ch)
+	case '/':
+		if l.regexAllowed() {
+			pattern, msg := l.readRegex()
+			if msg != "" {
+				tok = token.Token{Type: token.ILLEGAL, Literal: msg}
+			} else {
+				tok = token.Token{Type: token.REGEX, Literal: pattern}
+			}
+			tok.Text = string(l.lit)
+			tok.Pos = pos
+			return tok
 		}
-	case '+':
-		if l.peekChar() == '+' {
-			ch := l.ch
-			l.readChar()
-			tok = token.Token{Type: token.PLUSPLUS, Literal: string(ch) + string(l.ch)}
-		} else if l.peekChar() == '=' {
-			ch := l.ch
+	case ':':
+		if isLetter(l.peekChar()) && !endsOperand(l.prev) {
+			// :name in operand position is a symbol, after an operand it
+			// is a type annotation or a map key so it stays a COLON
 			l.readChar()
-			tok = token.Token{Type: token.PLUSEQ, Literal: string(ch) + string(l.ch)}
-		} else {
-			tok = newToken(token.PLUS, l.ch)
+			tok.Type = token.SYMBOL
+			tok.Literal = l.readIdentifier()
+			tok.Text = string(l.lit)
+			tok.Pos = pos
+			return tok
 		}
-	case '-':
-		if l.peekChar() == '-' {
-			ch := l.ch
-			l.readChar()
-			tok = token.Token{Type: token.MINUSMINUS, Literal: string(ch) + string(l.ch)}
-		} else if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			tok = token.Token{Type: token.MINUSEQ, Literal: string(ch) + string(l.ch)}
+	case '"':
+		l.readChar()
+		return l.readStringPart(true)
+	case '\'':
+		value, msg := l.readCharLiteral()
+		if msg != "" {
+			tok = token.Token{Type: token.ILLEGAL, Literal: msg}
 		} else {
-			tok = newToken(token.MINUS, l.ch)
+			tok = token.Token{Type: token.CHARLIT, Literal: value}
 		}
-	case '!':
-		if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			tok = token.Token{Type: token.NEQ, Literal: string(ch) + string(l.ch)}
+		tok.Text = string(l.lit)
+		tok.Pos = pos
+		return tok
+	case '`':
+		value, ok := l.readRawString()
+		if ok {
+			tok = token.Token{Type: token.STRINGLIT, Literal: value, Raw: true}
 		} else {
-			tok = newToken(token.BANG, l.ch)
+			msg := l.report(pos, UnterminatedString, "unterminated raw string literal")
+			tok = token.Token{Type: token.ILLEGAL, Literal: msg}
 		}
-	case '/':
-		if l.peekChar() == '/' {
-			ch := l.ch
-			l.readChar()
-			tok = token.Token{Type: token.FLOORDIV, Literal: string(ch) + string(l.ch)}
-		} else if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			tok = token.Token{Type: token.DIVEQ, Literal: string(ch) + string(l.ch)}
-		} else {
-			tok = newToken(token.FSLASH, l.ch)
+		tok.Text = string(l.lit)
+		tok.Pos = pos
+		return tok
+	case eof:
+		return token.Token{Type: token.EOF, Pos: pos}
+	}
+
+	if tokType, ok := l.readOperator(); ok {
+		if tokType == token.RBRACE && (l.top() == token.INTERPSTART || l.top() == token.INTERPDEBUG) {
+			tokType = token.INTERPEND
 		}
-	case '*':
-		if l.peekChar() == '*' {
-			ch := l.ch
-			l.readChar()
-			tok = token.Token{Type: token.POW, Literal: string(ch) + string(l.ch)}
-		} else if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			tok = token.Token{Type: token.MULEQ, Literal: string(ch) + string(l.ch)}
-		} else {
-			tok = newToken(token.ASTERISK, l.ch)
+		text := string(l.lit)
+		return token.Token{Type: tokType, Literal: text, Text: text, Pos: pos}
+	}
+
+	if isLetter(l.ch) {
+		tok.Literal = l.readIdentifier()
+		tok.Type = token.LookupIdent(tok.Literal)
+		if l.prev == token.DOT {
+			// a name after a dot is a member, even xs.map or x.type
+			tok.Type = token.IDENT
 		}
-	case '<':
-		if l.peekChar() == '<' {
-			ch := l.ch
-			l.readChar()
-			tok = token.Token{Type: token.BITLS, Literal: string(ch) + string(l.ch)}
-		} else if l.peekChar() == '=' {
-			ch := l.ch
-			l.readChar()
-			tok = token.Token{Type: token.LTE, Literal: string(ch) + string(l.ch)}
+		tok.Text = tok.Literal
+		tok.Pos = pos
+		return tok
+	} else if isDecimal(l.ch) {
+		lit, tokType, msg := l.readNumber()
+		if msg != "" {
+			tok = token.Token{Type: token.ILLEGAL, Literal: msg}
 		} else {
-			tok = newToken(token.LT, l.ch)
+			tok = token.Token{Type: tokType, Literal: lit}
+		}
+		tok.Text = lit
+		tok.Pos = pos
+		return tok
+	} else if l.bad != 0 {
+		msg := l.report(pos, InvalidUTF8, "invalid UTF-8 encoding")
+		tok = token.Token{Type: token.ILLEGAL, Literal: msg}
+	} else {
+		l.report(pos, UnexpectedChar, fmt.Sprintf("unexpected character %q", l.ch))
+		tok = newToken(token.ILLEGAL, l.ch)
+	}
+	l.readChar()
+	tok.Text = string(l.lit)
+	tok.Pos = pos
+	return tok
+}
+
+// SetMode changes which optional tokens are emitted by the lexer
+func (l *Lexer) SetMode(mode Mode) {
+	l.mode = mode
+}
+
+// position returns the source position of the current char
+func (l *Lexer) position() token.Position {
+	return token.Position{Filename: l.filename, Offset: l.pos, Line: l.line, Column: l.col}
+}
+
+// decode reads the next char from the input. Once the input is used up
+// every call returns eof, a reader that fails is reported once and then
+// treated the same as the end of the input
+func (l *Lexer) decode() char {
+	if l.readErr {
+		return char{r: eof}
+	}
+	r, size, err := l.r.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			l.readErr = true
+			l.report(l.decodePos(), ReadError, fmt.Sprintf("reading input: %s", err))
+		}
+		return char{r: eof}
+	}
+	if r == utf8.RuneError && size == 1 {
+		// go back and grab the offending byte so it can be reported and
+		// kept in the token text
+		l.r.UnreadRune()
+		b, _ := l.r.ReadByte()
+		return char{r: utf8.RuneError, size: 1, bad: b}
+	}
+	return char{r: r, size: size}
+}
+
+// decodePos returns the position of the char decode is about to read,
+// which comes after the current char and the ones peeked at
+func (l *Lexer) decodePos() token.Position {
+	pos := token.Position{Filename: l.filename, Offset: l.nextPos, Line: l.line, Column: l.col}
+	chars := append([]char{{r: l.ch}}, l.ahead...)
+	for i, c := range chars {
+		if i > 0 {
+			pos.Offset += c.size
 		}
-	case '>':
-		if l.peekChar() == '>' {
-			ch := l.ch
+		if c.r == '\n' {
+			pos.Line++
+			pos.Column = 0
+		}
+		pos.Column++
+	}
+	return pos
+}
+
+// peekCharAtOffset returns the char offset places ahead of the current
+// one without consuming anything, peekCharAtOffset(1) is the next char
+func (l *Lexer) peekCharAtOffset(offset int) rune {
+	for len(l.ahead) < offset {
+		l.ahead = append(l.ahead, l.decode())
+	}
+	return l.ahead[offset-1].r
+}
+
+func (l *Lexer) peekChar() rune {
+	return l.peekCharAtOffset(1)
+}
+
+// readChar consumes the character and keeps the line and column
+// in step with the new position
+func (l *Lexer) readChar() {
+	if l.ch != eof {
+		l.lit = l.appendChar(l.lit)
+	}
+	if l.ch == '\n' {
+		l.line++
+		l.col = 0
+	}
+
+	var next char
+	if len(l.ahead) > 0 {
+		// shift down instead of reslicing so the queue never has to grow again
+		next = l.ahead[0]
+		copy(l.ahead, l.ahead[1:])
+		l.ahead = l.ahead[:len(l.ahead)-1]
+	} else {
+		next = l.decode()
+	}
+	l.ch = next.r
+	l.bad = next.bad
+	l.pos = l.nextPos
+	l.nextPos += next.size
+	l.col++
+}
+
+// readStringPart reads the text of an interpolated string up to the
+// closing quote or the next `#{`, first is set when the opening quote has
+// just been consumed. A string with no interpolation at all is returned
+// as a single STRINGLIT
+func (l *Lexer) readStringPart(first bool) token.Token {
+	pos := l.position()
+	if first {
+		// the opening quote was already read
+		pos.Offset--
+		pos.Column--
+	} else {
+		l.lit = l.lit[:0]
+	}
+
+	value, interp, msg := l.readStringSegment(pos)
+	tok := token.Token{Literal: value, Pos: pos}
+	switch {
+	case msg != "":
+		tok.Type = token.ILLEGAL
+		tok.Literal = msg
+	case first && !interp:
+		tok.Type = token.STRINGLIT
+	case first:
+		tok.Type = token.STRINGSTART
+	case interp:
+		tok.Type = token.STRINGMID
+	default:
+		tok.Type = token.STRINGEND
+	}
+	tok.Text = string(l.lit)
+	return tok
+}
+
+// readStringSegment consumes string text with its escape sequences decoded
+// until the closing quote, which is consumed, or a `#{` which is left for
+// the next token and sets interp. msg describes the problem when the
+// string is malformed, in which case the rest of the string is skipped. A
+// string that hits a newline or the end of the input is unterminated and
+// the newline is left for the next token, the error is reported at start
+func (l *Lexer) readStringSegment(start token.Position) (value string, interp bool, msg string) {
+	var out []byte
+	for {
+		switch l.ch {
+		case '"':
 			l.readChar()
-			tok = token.Token{Type: token.BITRS, Literal: string(ch) + string(l.ch)}
-		} else if l.peekChar() == '=' {
-			ch := l.ch
+			return string(out), false, ""
+		case '\n', eof:
+			return string(out), false, l.report(start, UnterminatedString, "unterminated string literal")
+		case '#':
+			if l.peekChar() == '{' {
+				return string(out), true, ""
+			}
+			out = l.appendLiteralChar(out)
 			l.readChar()
-			tok = token.Token{Type: token.GTE, Literal: string(ch) + string(l.ch)}
-		} else {
-			tok = newToken(token.GT, l.ch)
-		}
-	case ';':
-		tok = newToken(token.SEMICOLON, l.ch)
-	case ',':
-		tok = newToken(token.COMMA, l.ch)
-	case '(':
-		tok = newToken(token.LPAREN, l.ch)
-	case ')':
-		tok = newToken(token.RPAREN, l.ch)
-	case '{':
-		tok = newToken(token.LBRACE, l.ch)
-	case '}':
-		tok = newToken(token.RBRACE, l.ch)
-	case '"':
-		tok.Type = token.STRINGLIT
-		tok.Literal = l.readString()
-	case '[':
-		tok = newToken(token.LBRACKET, l.ch)
-	case ']':
-		tok = newToken(token.RBRACKET, l.ch)
-	case ':':
-		tok = newToken(token.COLON, l.ch)
-	case '^':
-		if l.peekChar() == '=' {
-			ch := l.ch
+		case '\\':
+			out, msg = l.readEscape('"', out)
+			if msg != "" {
+				l.skipString()
+				return "", false, msg
+			}
+		default:
+			out = l.appendLiteralChar(out)
 			l.readChar()
-			tok = token.Token{Type: token.BITXOREQ, Literal: string(ch) + string(l.ch)}
-		} else {
-			tok = newToken(token.HAT, l.ch)
 		}
-	case '~':
-		if l.peekChar() == '=' {
-			ch := l.ch
+	}
+}
+
+// skipString consumes the rest of a malformed string so that lexing can
+// carry on after it
+func (l *Lexer) skipString() {
+	for l.ch != '"' && l.ch != '\n' && l.ch != eof {
+		if l.ch == '\\' && l.peekChar() == '"' {
 			l.readChar()
-			tok = token.Token{Type: token.BITNOTEQ, Literal: string(ch) + string(l.ch)}
-		} else {
-			tok = newToken(token.TILDE, l.ch)
 		}
-	case '.':
-		tok = newToken(token.DOT, l.ch)
-	case '%':
-		if l.peekChar() == '=' {
-			ch := l.ch
+		l.readChar()
+	}
+	if l.ch == '"' {
+		l.readChar()
+	}
+}
+
+// readInterpStart reads the `#{` or `#{=` that follows a STRINGSTART or
+// STRINGMID
+func (l *Lexer) readInterpStart() token.Token {
+	l.lit = l.lit[:0]
+	tok := token.Token{Type: token.INTERPSTART, Pos: l.position()}
+	l.readChar()
+	l.readChar()
+	if l.ch == '=' {
+		tok.Type = token.INTERPDEBUG
+		l.readChar()
+	}
+	tok.Literal = string(l.lit)
+	tok.Text = tok.Literal
+	return tok
+}
+
+// readCharLiteral consumes a single quoted character literal such as 'a'
+// or '\n', including both quotes. The literal has to hold exactly one code
+// point once escapes are decoded. msg describes the problem when it is
+// malformed, a literal that reaches a newline or the end of the input is
+// unterminated and the newline is left for the next token
+func (l *Lexer) readCharLiteral() (value string, msg string) {
+	start := l.position()
+	var out []byte
+	l.readChar()
+	for l.ch != '\'' {
+		switch l.ch {
+		case '\n', eof:
+			return string(out), l.report(start, UnterminatedString, "unterminated character literal")
+		case '\\':
+			var escMsg string
+			out, escMsg = l.readEscape('\'', out)
+			if msg == "" {
+				msg = escMsg
+			}
+		default:
+			out = l.appendLiteralChar(out)
 			l.readChar()
-			tok = token.Token{Type: token.MODEQ, Literal: string(ch) + string(l.ch)}
-		} else {
-			tok = newToken(token.PERCENT, l.ch)
 		}
-	case '&':
-		if l.peekChar() == '=' {
-			ch := l.ch
+	}
+	l.readChar()
+
+	if msg != "" {
+		return string(out), msg
+	}
+	if len(out) == 0 {
+		return "", l.report(start, MalformedLiteral, "empty character literal")
+	}
+	if !utf8.Valid(out) || utf8.RuneCount(out) != 1 {
+		return string(out), l.report(start, MalformedLiteral, "character literal must hold exactly one character")
+	}
+	return string(out), ""
+}
+
+// readRawString consumes a backtick string literal, including both
+// backticks. Nothing inside is escaped and it may span lines, carriage
+// returns are dropped from the value so files with windows line endings
+// give the same string. ok is false if the input ends before the closing
+// backtick
+func (l *Lexer) readRawString() (value string, ok bool) {
+	var out []byte
+	l.readChar()
+	for l.ch != '`' {
+		if l.ch == eof {
+			return string(out), false
+		}
+		if l.ch != '\r' {
+			out = l.appendLiteralChar(out)
+		}
+		l.readChar()
+	}
+	l.readChar()
+	return string(out), true
+}
+
+// readEscape decodes the escape sequence starting at the current backslash
+// and appends the result to out. quote is the delimiter of the literal
+// being read which is allowed to be escaped. On failure msg describes the
+// bad escape and as little as possible is consumed so the caller can
+// still find the end of the literal
+func (l *Lexer) readEscape(quote rune, out []byte) (_ []byte, msg string) {
+	start := l.position()
+	l.readChar()
+	switch l.ch {
+	case 'n':
+		out = append(out, '\n')
+	case 't':
+		out = append(out, '\t')
+	case 'r':
+		out = append(out, '\r')
+	case '0':
+		out = append(out, 0)
+	case 'a':
+		out = append(out, '\a')
+	case 'b':
+		out = append(out, '\b')
+	case 'f':
+		out = append(out, '\f')
+	case 'v':
+		out = append(out, '\v')
+	case '\\', '#', quote:
+		out = append(out, byte(l.ch))
+	case 'x':
+		// \xHH is always exactly two hex digits and is a single byte
+		var b int
+		for i := 0; i < 2; i++ {
+			d := digitVal(l.peekChar())
+			if d >= 16 {
+				return out, l.report(start, BadEscape, "\\x escape must be followed by two hex digits")
+			}
+			b = b*16 + d
 			l.readChar()
-			tok = token.Token{Type: token.BITANDEQ, Literal: string(ch) + string(l.ch)}
-		} else {
-			tok = newToken(token.AMPERSAND, l.ch)
 		}
-	case '|':
-		if l.peekChar() == '=' {
-			ch := l.ch
+		out = append(out, byte(b))
+	case 'u':
+		// \u{H...} holds one to six hex digits naming a code point
+		if l.peekChar() != '{' {
+			return out, l.report(start, BadEscape, "\\u escape must be followed by `{`")
+		}
+		l.readChar()
+		r, n := 0, 0
+		for digitVal(l.peekChar()) < 16 {
+			r = r*16 + digitVal(l.peekChar())
+			n++
 			l.readChar()
-			tok = token.Token{Type: token.BITOREQ, Literal: string(ch) + string(l.ch)}
-		} else {
-			tok = newToken(token.PIPE, l.ch)
 		}
-	case '`':
-		tok = newToken(token.BACKTICK, l.ch)
-	case '?':
-		tok = newToken(token.QUESTION, l.ch)
-	case 0:
-		tok.Literal = ""
-		tok.Type = token.EOF
-	default:
-		if isLetter(l.ch) {
-			tok.Literal = l.readIdentifier()
-			tok.Type = token.LookupIdent(tok.Literal)
-			return tok
-		} else if isDigit(l.ch) {
-			tok.Type = token.NUM
-			tok.Literal = l.readNumber()
-			return tok
-		} else {
-			tok = newToken(token.ILLEGAL, l.ch)
+		if n == 0 || n > 6 || l.peekChar() != '}' {
+			return out, l.report(start, BadEscape, "\\u{} escape must hold one to six hex digits")
 		}
+		l.readChar()
+		if !utf8.ValidRune(rune(r)) {
+			return out, l.report(start, BadEscape, fmt.Sprintf("\\u{%X} is not a valid unicode code point", r))
+		}
+		out = appendRune(out, rune(r))
+	case '\n', eof:
+		// leave the newline so the literal is reported as unterminated
+		return out, ""
+	default:
+		msg = l.report(start, BadEscape, fmt.Sprintf("unknown escape sequence \\%c", l.ch))
 	}
 	l.readChar()
-	return tok
+	return out, msg
 }
 
-func (l *Lexer) peekCharAtOffset(offset int) byte {
-	nextPos := l.pos + offset
-	if nextPos >= l.inputLen {
-		return 0
-	} else {
-		return l.input[nextPos]
+// appendChar appends the bytes of the current char to out, bytes that were
+// not valid UTF-8 are copied through untouched
+func (l *Lexer) appendChar(out []byte) []byte {
+	if l.bad != 0 {
+		return append(out, l.bad)
 	}
+	return appendRune(out, l.ch)
 }
 
-func (l *Lexer) peekChar() byte {
-	if l.nextPos >= l.inputLen {
-		return 0
-	} else {
-		return l.input[l.nextPos]
+// appendLiteralChar is appendChar for the contents of a literal, where
+// a byte that is not valid UTF-8 is reported but otherwise kept
+func (l *Lexer) appendLiteralChar(out []byte) []byte {
+	if l.bad != 0 {
+		l.report(l.position(), InvalidUTF8, "invalid UTF-8 encoding")
 	}
+	return l.appendChar(out)
 }
 
-// readChar consumes the character
-func (l *Lexer) readChar() {
-	if l.nextPos >= l.inputLen {
-		l.ch = 0
-	} else {
-		l.ch = l.input[l.nextPos]
+func appendRune(out []byte, r rune) []byte {
+	if r < utf8.RuneSelf {
+		return append(out, byte(r))
 	}
-	l.pos = l.nextPos
-	l.nextPos++
+	var buf [utf8.UTFMax]byte
+	n := utf8.EncodeRune(buf[:], r)
+	return append(out, buf[:n]...)
 }
 
-func (l *Lexer) readString() string {
-	position := l.pos + 1
-	for {
-		l.readChar()
-		if l.ch == '"' {
-			break
-		}
+// digitVal returns the value of ch as a hex digit, or 16 when ch is not one
+func digitVal(ch rune) int {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch - '0')
+	case 'a' <= ch && ch <= 'f':
+		return int(ch - 'a' + 10)
+	case 'A' <= ch && ch <= 'F':
+		return int(ch - 'A' + 10)
 	}
-	return l.input[position:l.pos]
+	return 16
 }
 
+// New returns a lexer for input, positions on its tokens have no filename
 func New(input string) *Lexer {
-	inputLen := len(input)
-	l := &Lexer{input: input, inputLen: inputLen}
+	return NewFile("", input)
+}
+
+// NewFile returns a lexer for input that was read from filename so that
+// token positions can point back at the file
+func NewFile(filename string, input string) *Lexer {
+	return NewReader(filename, strings.NewReader(input))
+}
+
+// NewReader returns a lexer that decodes UTF-8 from r as tokens are asked
+// for, so the whole input never has to be held in memory at once
+func NewReader(filename string, r io.Reader) *Lexer {
+	l := &Lexer{r: bufio.NewReader(r), ch: eof, filename: filename, line: 1}
 	l.readChar()
 	return l
 }
@@ -2,11 +2,16 @@ package lexer
 
 import (
 	"b/token"
+	"errors"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
 	"testing"
 )
 
 func TestNextTokenShort(t *testing.T) {
-	input := "=+(){},;`"
+	input := "=+(){},;`raw`"
 
 	tests := []struct {
 		expectedType    token.TokenType
@@ -20,7 +25,7 @@ func TestNextTokenShort(t *testing.T) {
 		{token.RBRACE, "}"},
 		{token.COMMA, ","},
 		{token.SEMICOLON, ";"},
-		{token.BACKTICK, "`"},
+		{token.STRINGLIT, "raw"},
 		{token.EOF, ""},
 	}
 
@@ -48,7 +53,7 @@ func TestNextTokenLong(t *testing.T) {
 	};
 	
 	val result = add(five, ten);
-	!-/*5;
+	!-5/*5;
 	5 < 10 > 5;
 
 	if (5 < 10) {
@@ -84,12 +89,12 @@ func TestNextTokenLong(t *testing.T) {
 		{token.VAR, "var"},
 		{token.IDENT, "five"},
 		{token.ASSIGN, "="},
-		{token.NUM, "5"},
+		{token.INTLIT, "5"},
 		{token.SEMICOLON, ";"},
 		{token.VAR, "var"},
 		{token.IDENT, "ten"},
 		{token.ASSIGN, "="},
-		{token.NUM, "10"},
+		{token.INTLIT, "10"},
 		{token.SEMICOLON, ";"},
 		{token.VAR, "var"},
 		{token.IDENT, "add"},
@@ -119,21 +124,22 @@ func TestNextTokenLong(t *testing.T) {
 		{token.SEMICOLON, ";"},
 		{token.BANG, "!"},
 		{token.MINUS, "-"},
+		{token.INTLIT, "5"},
 		{token.FSLASH, "/"},
 		{token.ASTERISK, "*"},
-		{token.NUM, "5"},
+		{token.INTLIT, "5"},
 		{token.SEMICOLON, ";"},
-		{token.NUM, "5"},
+		{token.INTLIT, "5"},
 		{token.LT, "<"},
-		{token.NUM, "10"},
+		{token.INTLIT, "10"},
 		{token.GT, ">"},
-		{token.NUM, "5"},
+		{token.INTLIT, "5"},
 		{token.SEMICOLON, ";"},
 		{token.IF, "if"},
 		{token.LPAREN, "("},
-		{token.NUM, "5"},
+		{token.INTLIT, "5"},
 		{token.LT, "<"},
-		{token.NUM, "10"},
+		{token.INTLIT, "10"},
 		{token.RPAREN, ")"},
 		{token.LBRACE, "{"},
 		{token.RETURN, "return"},
@@ -146,14 +152,17 @@ func TestNextTokenLong(t *testing.T) {
 		{token.FALSE, "false"},
 		{token.SEMICOLON, ";"},
 		{token.RBRACE, "}"},
+		{token.SEMICOLON, "\n"},
 		{token.EQ, "=="},
 		{token.NEQ, "!="},
 		{token.STRINGLIT, "foobar"},
+		{token.SEMICOLON, "\n"},
 		{token.STRINGLIT, "foo bar"},
+		{token.SEMICOLON, "\n"},
 		{token.LBRACKET, "["},
-		{token.NUM, "1"},
+		{token.INTLIT, "1"},
 		{token.COMMA, ","},
-		{token.NUM, "2"},
+		{token.INTLIT, "2"},
 		{token.RBRACKET, "]"},
 		{token.SEMICOLON, ";"},
 		{token.LBRACE, "{"},
@@ -161,6 +170,7 @@ func TestNextTokenLong(t *testing.T) {
 		{token.COLON, ":"},
 		{token.STRINGLIT, "bar"},
 		{token.RBRACE, "}"},
+		{token.SEMICOLON, "\n"},
 		{token.MACRO, "macro"},
 		{token.LPAREN, "("},
 		{token.IDENT, "x"},
@@ -185,16 +195,20 @@ func TestNextTokenLong(t *testing.T) {
 		{token.AMPERSAND, "&"},
 		{token.PERCENT, "%"},
 		{token.PIPE, "|"},
-		{token.NUM, "0_10"},
-		{token.NUM, "0b10_111"},
-		{token.NUM, "0B1"},
-		{token.NUM, "0x1"},
-		{token.NUM, "0X1"},
-		{token.NUM, "0o1"},
-		{token.NUM, "0O1"},
-		{token.IDENT, "abc"},
-		{token.NUM, "0x123_"},
-		{token.IDENT, "abc_123"},
+		{token.INTLIT, "0_10"},
+		{token.SEMICOLON, "\n"},
+		{token.INTLIT, "0b10_111"},
+		{token.SEMICOLON, "\n"},
+		{token.INTLIT, "0B1"},
+		{token.SEMICOLON, "\n"},
+		{token.INTLIT, "0x1"},
+		{token.SEMICOLON, "\n"},
+		{token.INTLIT, "0X1"},
+		{token.SEMICOLON, "\n"},
+		{token.INTLIT, "0o1"},
+		{token.SEMICOLON, "\n"},
+		{token.ILLEGAL, "invalid character 'a' in octal literal"},
+		{token.INTLIT, "0x123_abc_123"},
 		{token.POW, "**"},
 		{token.EOF, ""},
 	}
@@ -249,12 +263,16 @@ func TestNextTokenShort2(t *testing.T) {
 		{token.IN, "in"},
 		{token.FOR, "for"},
 		{token.IDENT, "infor"},
+		{token.SEMICOLON, "\n"},
 		{token.QUESTION, "?"},
-		{token.NUM, "0.1234"},
+		{token.SEMICOLON, "\n"},
+		{token.FLOATLIT, "0.1234"},
+		{token.SEMICOLON, "\n"},
 		{token.IDENT, "abc"},
 		{token.DOT, "."},
 		{token.IDENT, "two"},
-		{token.NUM, "0.32"},
+		{token.FLOATLIT, "0.32"},
+		{token.SEMICOLON, "\n"},
 		{token.INT, "int"},
 		{token.UINT, "uint"},
 		{token.FLOAT, "float"},
@@ -265,10 +283,420 @@ func TestNextTokenShort2(t *testing.T) {
 		{token.MAP, "map"},
 		{token.CHANNEL, "chan"},
 		{token.ANY, "any"},
+		{token.SEMICOLON, "\n"},
 		{token.BOOLEAN, "bool"},
 		{token.CHARACTER, "char"},
 		{token.RUNE, "rune"},
 		{token.SET, "set"},
+		{token.SEMICOLON, "\n"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNextTokenPositions(t *testing.T) {
+	input := `var x = 5;
+  x == 10
+`
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+		expectedOffset int
+	}{
+		{token.VAR, 1, 1, 0},
+		{token.IDENT, 1, 5, 4},
+		{token.ASSIGN, 1, 7, 6},
+		{token.INTLIT, 1, 9, 8},
+		{token.SEMICOLON, 1, 10, 9},
+		{token.IDENT, 2, 3, 13},
+		{token.EQ, 2, 5, 15},
+		{token.INTLIT, 2, 8, 18},
+		{token.SEMICOLON, 2, 10, 20},
+		{token.EOF, 3, 1, 21},
+	}
+
+	l := NewFile("test.b", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos.Filename != "test.b" {
+			t.Fatalf("test[%d] - filename wrong. expected=%q, got=%q", i, "test.b", tok.Pos.Filename)
+		}
+
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("test[%d] - line:column wrong. expected=%d:%d, got=%d:%d", i,
+				tt.expectedLine, tt.expectedColumn, tok.Pos.Line, tok.Pos.Column)
+		}
+
+		if tok.Pos.Offset != tt.expectedOffset {
+			t.Fatalf("test[%d] - offset wrong. expected=%d, got=%d", i, tt.expectedOffset, tok.Pos.Offset)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `# Single Line Comment
+	var x = 1 # trailing
+	###
+		Block Comment # with a pound
+	###
+	x ## still a line comment
+	###`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.COMMENT, "# Single Line Comment"},
+		{token.VAR, "var"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INTLIT, "1"},
+		{token.COMMENT, "# trailing"},
+		{token.SEMICOLON, "\n"},
+		{token.COMMENT, "###\n\t\tBlock Comment # with a pound\n\t###"},
+		{token.IDENT, "x"},
+		{token.COMMENT, "## still a line comment"},
+		{token.SEMICOLON, "\n"},
+		{token.ILLEGAL, "unterminated block comment"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	l.SetMode(ScanComments)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	// without ScanComments the comments are skipped entirely
+	l = New(input)
+	expected := []token.TokenType{token.VAR, token.IDENT, token.ASSIGN, token.INTLIT, token.SEMICOLON,
+		token.IDENT, token.SEMICOLON, token.ILLEGAL, token.EOF}
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("skip[%d] - tokentype wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+}
+
+func TestNextTokenUnicode(t *testing.T) {
+	input := "val größe = \"héllo wörld\"\n名前 \xff x"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{token.VAL, "val", 1, 1},
+		{token.IDENT, "größe", 1, 5},
+		{token.ASSIGN, "=", 1, 11},
+		{token.STRINGLIT, "héllo wörld", 1, 13},
+		{token.SEMICOLON, "\n", 1, 26},
+		{token.IDENT, "名前", 2, 1},
+		{token.ILLEGAL, "invalid UTF-8 encoding", 2, 4},
+		{token.IDENT, "x", 2, 6},
+		{token.EOF, "", 2, 7},
+	}
+
+	l := NewReader("test.b", strings.NewReader(input))
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.Line != tt.expectedLine || tok.Pos.Column != tt.expectedColumn {
+			t.Fatalf("test[%d] - line:column wrong. expected=%d:%d, got=%d:%d", i,
+				tt.expectedLine, tt.expectedColumn, tok.Pos.Line, tok.Pos.Column)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	input := `"a\"b" "\n\t\\" "\u{1F600}\x41" "\q" "\x4" "\u{110000}" "open
+"abc\`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedText    string
+	}{
+		{token.STRINGLIT, `a"b`, `"a\"b"`},
+		{token.STRINGLIT, "\n\t\\", `"\n\t\\"`},
+		{token.STRINGLIT, "😀A", `"\u{1F600}\x41"`},
+		{token.ILLEGAL, `unknown escape sequence \q`, `"\q"`},
+		{token.ILLEGAL, `\x escape must be followed by two hex digits`, `"\x4"`},
+		{token.ILLEGAL, `\u{110000} is not a valid unicode code point`, `"\u{110000}"`},
+		{token.ILLEGAL, "unterminated string literal", `"open`},
+		{token.ILLEGAL, "unterminated string literal", `"abc\`},
+		{token.EOF, "", ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Text != tt.expectedText {
+			t.Fatalf("test[%d] - text wrong. expected=%q, got=%q", i, tt.expectedText, tok.Text)
+		}
+	}
+}
+
+func TestRawStrings(t *testing.T) {
+	input := "`C:\\path\\n [0-9]+\\.` `line one\r\nline \"two\"` \"\\n\" `open"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedRaw     bool
+	}{
+		{token.STRINGLIT, `C:\path\n [0-9]+\.`, true},
+		{token.STRINGLIT, "line one\nline \"two\"", true},
+		{token.STRINGLIT, "\n", false},
+		{token.ILLEGAL, "unterminated raw string literal", false},
+		{token.EOF, "", false},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Raw != tt.expectedRaw {
+			t.Fatalf("test[%d] - raw wrong. expected=%t, got=%t", i, tt.expectedRaw, tok.Raw)
+		}
+	}
+}
+
+func TestStringInterpolation(t *testing.T) {
+	input := `"Hey #{name}!" "#{=o.name}, #{ {"k": "#{v}"}["k"] } \#{x} #1" "#{a}"`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRINGSTART, "Hey "},
+		{token.INTERPSTART, "#{"},
+		{token.IDENT, "name"},
+		{token.INTERPEND, "}"},
+		{token.STRINGEND, "!"},
+		{token.STRINGSTART, ""},
+		{token.INTERPDEBUG, "#{="},
+		{token.IDENT, "o"},
+		{token.DOT, "."},
+		{token.IDENT, "name"},
+		{token.INTERPEND, "}"},
+		{token.STRINGMID, ", "},
+		{token.INTERPSTART, "#{"},
+		{token.LBRACE, "{"},
+		{token.STRINGLIT, "k"},
+		{token.COLON, ":"},
+		{token.STRINGSTART, ""},
+		{token.INTERPSTART, "#{"},
+		{token.IDENT, "v"},
+		{token.INTERPEND, "}"},
+		{token.STRINGEND, ""},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.STRINGLIT, "k"},
+		{token.RBRACKET, "]"},
+		{token.INTERPEND, "}"},
+		{token.STRINGEND, " #{x} #1"},
+		{token.STRINGSTART, ""},
+		{token.INTERPSTART, "#{"},
+		{token.IDENT, "a"},
+		{token.INTERPEND, "}"},
+		{token.STRINGEND, ""},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	var text string
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		text += tok.Text
+	}
+
+	// only the whitespace between tokens outside of the strings is missing
+	expected := `"Hey #{name}!""#{=o.name}, #{{"k":"#{v}"}["k"]} \#{x} #1""#{a}"`
+	if text != expected {
+		t.Fatalf("token text wrong. expected=%q, got=%q", expected, text)
+	}
+}
+
+func TestRegexLiterals(t *testing.T) {
+	input := `val re = /[0-9]{1,}/i
+	x / 2 / y
+	find(/a\/b[/]c\d/, s) // 2
+	f(/x/ims)
+	a /= 2
+	/unterminated
+	/bad/q`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.VAL, "val"},
+		{token.IDENT, "re"},
+		{token.ASSIGN, "="},
+		{token.REGEX, "[0-9]{1,}"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "x"},
+		{token.FSLASH, "/"},
+		{token.INTLIT, "2"},
+		{token.FSLASH, "/"},
+		{token.IDENT, "y"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "find"},
+		{token.LPAREN, "("},
+		{token.REGEX, `a/b[/]c\d`},
+		{token.COMMA, ","},
+		{token.IDENT, "s"},
+		{token.RPAREN, ")"},
+		{token.FLOORDIV, "//"},
+		{token.INTLIT, "2"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.REGEX, "x"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "a"},
+		{token.DIVEQ, "/="},
+		{token.INTLIT, "2"},
+		{token.SEMICOLON, "\n"},
+		{token.ILLEGAL, "unterminated regex literal"},
+		{token.ILLEGAL, "unknown regex flag 'q'"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if i == 3 && tok.RegexFlags() != "i" {
+			t.Fatalf("test[%d] - regex flags wrong. expected=%q, got=%q", i, "i", tok.RegexFlags())
+		}
+
+		if i == 22 && tok.RegexFlags() != "ims" {
+			t.Fatalf("test[%d] - regex flags wrong. expected=%q, got=%q", i, "ims", tok.RegexFlags())
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	input := `0xFF 0o17 0b1010 1_000_000 0x_1f 1.5 1e-9 2.5E+10 3e5 1..10 7.foo
+	0x 0b102 0o8 1__0 100_ 1_.5 1e 0b_
+	123abc 1e5x 1.5f 0x1p3 0b1z 0x 0xg`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INTLIT, "0xFF"},
+		{token.INTLIT, "0o17"},
+		{token.INTLIT, "0b1010"},
+		{token.INTLIT, "1_000_000"},
+		{token.INTLIT, "0x_1f"},
+		{token.FLOATLIT, "1.5"},
+		{token.FLOATLIT, "1e-9"},
+		{token.FLOATLIT, "2.5E+10"},
+		{token.FLOATLIT, "3e5"},
+		{token.INTLIT, "1"},
+		{token.DOTDOT, ".."},
+		{token.INTLIT, "10"},
+		{token.INTLIT, "7"},
+		{token.DOT, "."},
+		{token.IDENT, "foo"},
+		{token.SEMICOLON, "\n"},
+		{token.ILLEGAL, "hexadecimal literal has no digits"},
+		{token.ILLEGAL, "invalid digit '2' in binary literal"},
+		{token.ILLEGAL, "invalid digit '8' in octal literal"},
+		{token.ILLEGAL, "'_' must separate successive digits"},
+		{token.ILLEGAL, "'_' must separate successive digits"},
+		{token.ILLEGAL, "'_' must separate successive digits"},
+		{token.ILLEGAL, "exponent has no digits"},
+		{token.ILLEGAL, "binary literal has no digits"},
+		{token.ILLEGAL, "invalid character 'a' in decimal literal"},
+		{token.ILLEGAL, "invalid character 'x' in decimal literal"},
+		{token.ILLEGAL, "invalid character 'f' in decimal literal"},
+		{token.ILLEGAL, "invalid character 'p' in hexadecimal literal"},
+		{token.ILLEGAL, "invalid character 'z' in binary literal"},
+		{token.ILLEGAL, "hexadecimal literal has no digits"},
+		{token.ILLEGAL, "hexadecimal literal has no digits"},
 		{token.EOF, ""},
 	}
 
@@ -286,3 +714,609 @@ func TestNextTokenShort2(t *testing.T) {
 		}
 	}
 }
+
+func TestOperatorTable(t *testing.T) {
+	for _, op := range token.Operators() {
+		// an operand first so `/` is not the start of a regex and `:` is
+		// not the start of a symbol
+		l := New("x " + op + " y")
+		l.NextToken()
+
+		tok := l.NextToken()
+		if tok.Type != token.LookupOperator(op) {
+			t.Errorf("%q - tokentype wrong. expected=%q, got=%q", op, token.LookupOperator(op), tok.Type)
+		}
+		if tok.Literal != op {
+			t.Errorf("%q - literal wrong. got=%q", op, tok.Literal)
+		}
+	}
+}
+
+func TestMultiCharOperators(t *testing.T) {
+	input := `=> -> <- && || **= //= <<= >>= .. ... ..< 1..<10 |x, y| => x**2 ===`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.RARROW, "=>"},
+		{token.ARROW, "->"},
+		{token.LARROW, "<-"},
+		{token.LAND, "&&"},
+		{token.LOR, "||"},
+		{token.POWEQ, "**="},
+		{token.FLOORDIVEQ, "//="},
+		{token.BITLSEQ, "<<="},
+		{token.BITRSEQ, ">>="},
+		{token.DOTDOT, ".."},
+		{token.DOTDOTDOT, "..."},
+		{token.DOTDOTLT, "..<"},
+		{token.INTLIT, "1"},
+		{token.DOTDOTLT, "..<"},
+		{token.INTLIT, "10"},
+		{token.PIPE, "|"},
+		{token.IDENT, "x"},
+		{token.COMMA, ","},
+		{token.IDENT, "y"},
+		{token.PIPE, "|"},
+		{token.RARROW, "=>"},
+		{token.IDENT, "x"},
+		{token.POW, "**"},
+		{token.INTLIT, "2"},
+		{token.EQ, "=="},
+		{token.ASSIGN, "="},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestDivisionOnContinuationLine(t *testing.T) {
+	input := `f(a
+  / 2)
+x = (total
+  / count)
+xs[n
+  / 2]
+y
+/re/`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.FSLASH, "/"},
+		{token.INTLIT, "2"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.LPAREN, "("},
+		{token.IDENT, "total"},
+		{token.FSLASH, "/"},
+		{token.IDENT, "count"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "xs"},
+		{token.LBRACKET, "["},
+		{token.IDENT, "n"},
+		{token.FSLASH, "/"},
+		{token.INTLIT, "2"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "y"},
+		{token.SEMICOLON, "\n"},
+		{token.REGEX, "re"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestKeywordMemberNames(t *testing.T) {
+	input := `val t = x.type
+-1
+x.type / 2
+x.in
+/re/
+xs.map(f)`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.VAL, "val"},
+		{token.IDENT, "t"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "x"},
+		{token.DOT, "."},
+		{token.IDENT, "type"},
+		{token.SEMICOLON, "\n"},
+		{token.MINUS, "-"},
+		{token.INTLIT, "1"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "x"},
+		{token.DOT, "."},
+		{token.IDENT, "type"},
+		{token.FSLASH, "/"},
+		{token.INTLIT, "2"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "x"},
+		{token.DOT, "."},
+		{token.IDENT, "in"},
+		{token.SEMICOLON, "\n"},
+		{token.REGEX, "re"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "xs"},
+		{token.DOT, "."},
+		{token.IDENT, "map"},
+		{token.LPAREN, "("},
+		{token.IDENT, "f"},
+		{token.RPAREN, ")"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestSemicolonInsertion(t *testing.T) {
+	input := `var x = 1
+	x = 4 # Works
+	val y = add(1,
+		2) +
+		3
+	val z = [
+		1,
+	]
+	return
+	if x {
+		x
+	}
+	var t : int
+	`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.VAR, "var"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INTLIT, "1"},
+		{token.SEMICOLON, "\n"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INTLIT, "4"},
+		{token.SEMICOLON, "\n"},
+		{token.VAL, "val"},
+		{token.IDENT, "y"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "add"},
+		{token.LPAREN, "("},
+		{token.INTLIT, "1"},
+		{token.COMMA, ","},
+		{token.INTLIT, "2"},
+		{token.RPAREN, ")"},
+		{token.PLUS, "+"},
+		{token.INTLIT, "3"},
+		{token.SEMICOLON, "\n"},
+		{token.VAL, "val"},
+		{token.IDENT, "z"},
+		{token.ASSIGN, "="},
+		{token.LBRACKET, "["},
+		{token.INTLIT, "1"},
+		{token.COMMA, ","},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, "\n"},
+		{token.RETURN, "return"},
+		{token.SEMICOLON, "\n"},
+		{token.IF, "if"},
+		{token.IDENT, "x"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, "\n"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, "\n"},
+		{token.VAR, "var"},
+		{token.IDENT, "t"},
+		{token.COLON, ":"},
+		{token.INT, "int"},
+		{token.SEMICOLON, "\n"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestCharLiterals(t *testing.T) {
+	input := `'a' '\n' 'é' '\'' '\u{1F600}' '' 'ab' '\q' 'x`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.CHARLIT, "a"},
+		{token.CHARLIT, "\n"},
+		{token.CHARLIT, "é"},
+		{token.CHARLIT, "'"},
+		{token.CHARLIT, "😀"},
+		{token.ILLEGAL, "empty character literal"},
+		{token.ILLEGAL, "character literal must hold exactly one character"},
+		{token.ILLEGAL, `unknown escape sequence \q`},
+		{token.ILLEGAL, "unterminated character literal"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	input := `x = { :name, "John" }
+	val y : int = f(:a, :b_1)
+	{k: v, k2:v2}
+	return :ok`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.LBRACE, "{"},
+		{token.SYMBOL, "name"},
+		{token.COMMA, ","},
+		{token.STRINGLIT, "John"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, "\n"},
+		{token.VAL, "val"},
+		{token.IDENT, "y"},
+		{token.COLON, ":"},
+		{token.INT, "int"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.SYMBOL, "a"},
+		{token.COMMA, ","},
+		{token.SYMBOL, "b_1"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, "\n"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "k"},
+		{token.COLON, ":"},
+		{token.IDENT, "v"},
+		{token.COMMA, ","},
+		{token.IDENT, "k2"},
+		{token.COLON, ":"},
+		{token.IDENT, "v2"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, "\n"},
+		{token.RETURN, "return"},
+		{token.SYMBOL, "ok"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestKeywords(t *testing.T) {
+	input := `match type pub interface import break continue yield test is defer
+	i8 i16 i32 i64 u8 u16 u32 u64 f32 f64 i128`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.MATCH, "match"},
+		{token.TYPE, "type"},
+		{token.PUB, "pub"},
+		{token.INTERFACE, "interface"},
+		{token.IMPORT, "import"},
+		{token.BREAK, "break"},
+		{token.CONTINUE, "continue"},
+		{token.YIELD, "yield"},
+		{token.TEST, "test"},
+		{token.IS, "is"},
+		{token.DEFER, "defer"},
+		{token.I8, "i8"},
+		{token.I16, "i16"},
+		{token.I32, "i32"},
+		{token.I64, "i64"},
+		{token.U8, "u8"},
+		{token.U16, "u16"},
+		{token.U32, "u32"},
+		{token.U64, "u64"},
+		{token.F32, "f32"},
+		{token.F64, "f64"},
+		{token.IDENT, "i128"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestLexerErrors(t *testing.T) {
+	input := `x @ 0b12
+	"a\qb" 'ab'
+	"never closed
+	### open`
+
+	tests := []struct {
+		expectedKind ErrorKind
+		expectedMsg  string
+		expectedPos  string
+	}{
+		{UnexpectedChar, "unexpected character '@'", "test.b:1:3"},
+		{MalformedNumber, "invalid digit '2' in binary literal", "test.b:1:5"},
+		{BadEscape, `unknown escape sequence \q`, "test.b:2:4"},
+		{MalformedLiteral, "character literal must hold exactly one character", "test.b:2:9"},
+		{UnterminatedString, "unterminated string literal", "test.b:3:2"},
+		{UnterminatedComment, "unterminated block comment", "test.b:4:2"},
+	}
+
+	l := NewFile("test.b", input)
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+
+	errors := l.Errors()
+	if len(errors) != len(tests) {
+		t.Fatalf("wrong number of errors. expected=%d, got=%d (%v)", len(tests), len(errors), errors)
+	}
+
+	for i, tt := range tests {
+		err := errors[i]
+		if err.Kind != tt.expectedKind {
+			t.Errorf("errors[%d] - kind wrong. expected=%s, got=%s", i, tt.expectedKind, err.Kind)
+		}
+		if err.Msg != tt.expectedMsg {
+			t.Errorf("errors[%d] - msg wrong. expected=%q, got=%q", i, tt.expectedMsg, err.Msg)
+		}
+		if err.Pos.String() != tt.expectedPos {
+			t.Errorf("errors[%d] - pos wrong. expected=%s, got=%s", i, tt.expectedPos, err.Pos)
+		}
+	}
+}
+
+// failingReader returns data and then fails with err
+type failingReader struct {
+	data string
+	err  error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.data == "" {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestReadError(t *testing.T) {
+	r := &failingReader{data: "x = 1\nyz", err: errors.New("disk on fire")}
+	l := NewReader("test.b", r)
+
+	expected := []token.TokenType{token.IDENT, token.ASSIGN, token.INTLIT, token.SEMICOLON, token.IDENT, token.EOF, token.EOF}
+	for i, tt := range expected {
+		tok := l.NextToken()
+		if tok.Type != tt {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt, tok.Type)
+		}
+	}
+
+	errs := l.Errors()
+	if len(errs) != 1 {
+		t.Fatalf("wrong number of errors. expected=1, got=%d (%v)", len(errs), errs)
+	}
+	if errs[0].Kind != ReadError {
+		t.Errorf("kind wrong. expected=%s, got=%s", ReadError, errs[0].Kind)
+	}
+	if errs[0].Msg != "reading input: disk on fire" {
+		t.Errorf("msg wrong. got=%q", errs[0].Msg)
+	}
+	if errs[0].Pos.String() != "test.b:2:3" {
+		t.Errorf("pos wrong. expected=test.b:2:3, got=%s", errs[0].Pos)
+	}
+
+	// running out of input is not an error
+	l = NewReader("test.b", &failingReader{data: "x", err: io.EOF})
+	for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+	}
+	if len(l.Errors()) != 0 {
+		t.Errorf("expected no errors at the end of the input. got=%v", l.Errors())
+	}
+}
+
+// lexSource lexes input keeping trivia and joins the source of every token
+func lexSource(filename, input string) string {
+	l := NewFile(filename, input)
+	l.SetMode(ScanTrivia)
+	var b strings.Builder
+	for {
+		tok := l.NextToken()
+		b.WriteString(tok.Source())
+		if tok.Type == token.EOF {
+			return b.String()
+		}
+	}
+}
+
+func TestTriviaRoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		"  \n\t",
+		"x = 4 # Works\r\n\ty  ### block\n comment ###  z\n",
+		"print(\"a #{ x } b #{= y + 1}c\")  \n",
+		"val s = `raw\r\n`; f(/re\\/x/i, 'c', :sym) ###",
+		"\"never closed\n@ 0b12 \xff",
+	}
+	for i, input := range inputs {
+		if got := lexSource("test.b", input); got != input {
+			t.Errorf("inputs[%d] - round trip wrong. expected=%q, got=%q", i, input, got)
+		}
+	}
+}
+
+func TestTriviaRoundTripFile(t *testing.T) {
+	data, err := ioutil.ReadFile("../b.b")
+	if err != nil {
+		t.Fatalf("could not read b.b: %v", err)
+	}
+	if got := lexSource("b.b", string(data)); got != string(data) {
+		t.Fatalf("round trip of b.b wrong. expected=%q, got=%q", data, got)
+	}
+}
+
+func TestTrivia(t *testing.T) {
+	input := "  x = 4 # four\n\n### c ### y\n"
+
+	tests := []struct {
+		expectedType     token.TokenType
+		expectedLeading  []token.Trivia
+		expectedTrailing []token.Trivia
+	}{
+		{token.IDENT, []token.Trivia{{Kind: token.Whitespace, Text: "  "}}, []token.Trivia{{Kind: token.Whitespace, Text: " "}}},
+		{token.ASSIGN, nil, []token.Trivia{{Kind: token.Whitespace, Text: " "}}},
+		{token.INTLIT, nil, []token.Trivia{{Kind: token.Whitespace, Text: " "}, {Kind: token.Comment, Text: "# four"}}},
+		{token.SEMICOLON, nil, nil},
+		{token.IDENT, []token.Trivia{{Kind: token.Newline, Text: "\n"}, {Kind: token.Comment, Text: "### c ###"}, {Kind: token.Whitespace, Text: " "}}, nil},
+		{token.SEMICOLON, nil, nil},
+		{token.EOF, nil, nil},
+	}
+
+	l := New(input)
+	l.SetMode(ScanTrivia)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%d] - tokentype wrong. expected=%q, got=%q", i, tt.expectedType, tok.Type)
+		}
+		if !reflect.DeepEqual(tok.Leading, tt.expectedLeading) {
+			t.Fatalf("test[%d] - leading trivia wrong. expected=%q, got=%q", i, tt.expectedLeading, tok.Leading)
+		}
+		if !reflect.DeepEqual(tok.Trailing, tt.expectedTrailing) {
+			t.Fatalf("test[%d] - trailing trivia wrong. expected=%q, got=%q", i, tt.expectedTrailing, tok.Trailing)
+		}
+	}
+}
+
+// benchInput is a large input that mixes the contents of b.b with lines
+// that are mostly operators
+func benchInput(b *testing.B) string {
+	data, err := ioutil.ReadFile("../b.b")
+	if err != nil {
+		b.Fatalf("could not read b.b: %v", err)
+	}
+	ops := "x += a ** 2 // b - c % d <<= e >> f && g || !h != i == j ..< k ... l -> m <- n\n"
+	return strings.Repeat(string(data)+ops, 500)
+}
+
+func BenchmarkNextToken(b *testing.B) {
+	input := benchInput(b)
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l := New(input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+	}
+}
+
+func BenchmarkOperators(b *testing.B) {
+	input := strings.Repeat("+ += ++ - -= -- -> * ** **= / // //= < <= << <<= <- > >= >> >>= . .. ... ..< = == => ! != & && | || ", 500)
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l := New(input)
+		for tok := l.NextToken(); tok.Type != token.EOF; tok = l.NextToken() {
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package lexer
+
+import (
+	"b/token"
+	"unicode/utf8"
+)
+
+// opNode is a node in the trie of operators. Every operator is ASCII so
+// the children are indexed by byte, typ is set when the path from the
+// root spells a whole operator
+type opNode struct {
+	typ  token.TokenType
+	next [utf8.RuneSelf]*opNode
+}
+
+var operators = buildOperators(token.Operators())
+
+// buildOperators returns the root of a trie that holds every text in ops
+func buildOperators(ops []string) *opNode {
+	root := &opNode{}
+	for _, op := range ops {
+		node := root
+		for i := 0; i < len(op); i++ {
+			if node.next[op[i]] == nil {
+				node.next[op[i]] = &opNode{}
+			}
+			node = node.next[op[i]]
+		}
+		node.typ = token.LookupOperator(op)
+	}
+	return root
+}
+
+// readOperator consumes the longest operator that starts at the current
+// char and returns its type, ok is false when no operator starts here
+func (l *Lexer) readOperator() (tokType token.TokenType, ok bool) {
+	node := operators
+	n := 0
+	for i, ch := 0, l.ch; ch >= 0 && ch < utf8.RuneSelf; ch = l.peekCharAtOffset(i) {
+		node = node.next[ch]
+		if node == nil {
+			break
+		}
+		i++
+		if node.typ != "" {
+			tokType, n = node.typ, i
+		}
+	}
+	for i := 0; i < n; i++ {
+		l.readChar()
+	}
+	return tokType, n > 0
+}
@@ -0,0 +1,178 @@
+package lexer
+
+import (
+	"b/token"
+	"bufio"
+	"strings"
+	"unicode/utf8"
+)
+
+// State is everything the lexer carries from one token to the next. It is
+// taken between two calls to NextToken and can be used to pick up lexing
+// from the same point with Resume
+type State struct {
+	Pos  token.Position // position of the next char to be read
+	Mode Mode
+
+	prev  token.TokenType
+	stack []token.TokenType
+}
+
+// State returns a snapshot of the lexer that later calls to NextToken do
+// not change
+func (l *Lexer) State() State {
+	return State{
+		Pos:   l.position(),
+		Mode:  l.mode,
+		prev:  l.prev,
+		stack: append([]token.TokenType(nil), l.stack...),
+	}
+}
+
+// Resume returns a lexer over src that carries on from st as if it had
+// lexed src from the start. src has to be the whole input, not just the
+// part after st.Pos
+func Resume(src string, st State) *Lexer {
+	l := &Lexer{
+		r:        bufio.NewReader(strings.NewReader(src[st.Pos.Offset:])),
+		nextPos:  st.Pos.Offset,
+		ch:       eof,
+		filename: st.Pos.Filename,
+		line:     st.Pos.Line,
+		col:      st.Pos.Column - 1,
+		mode:     st.Mode,
+		prev:     st.prev,
+		stack:    append([]token.TokenType(nil), st.stack...),
+	}
+	l.readChar()
+	return l
+}
+
+// Edit replaces the bytes Start up to End of a source with Text
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// lookahead is the most bytes past the end of a token the lexer can have
+// looked at while reading it, it never peeks more than two chars ahead
+const lookahead = 2 * utf8.UTFMax
+
+// Relex returns the tokens of src given the tokens old of the source
+// before edit was applied. old must run up to EOF and have been lexed in
+// mode. Only the tokens around the edit are lexed again, once the new
+// tokens line up with the old ones the rest of old is reused with its
+// positions shifted. Errors are not collected, the ILLEGAL tokens still
+// carry their messages
+func Relex(old []token.Token, src string, edit Edit, mode Mode) []token.Token {
+	toks, _ := relex(old, src, edit, mode)
+	return toks
+}
+
+// relex does the work of Relex and also returns how many tokens had to be
+// lexed again
+func relex(old []token.Token, src string, edit Edit, mode Mode) ([]token.Token, int) {
+	// tokens that the edit could not have changed are kept as they are
+	keep := 0
+	for keep < len(old) && tokenEnd(old[keep])+lookahead < edit.Start {
+		keep++
+	}
+
+	// replay the kept tokens to get the state of the lexer right after them
+	replay := &Lexer{mode: mode}
+	for _, tok := range old[:keep] {
+		replay.track(tok)
+	}
+	st := replay.State()
+	st.Pos = token.Position{Line: 1, Column: 1}
+	if keep > 0 {
+		last := old[keep-1]
+		st.Pos = advance(last.Pos, src[last.Pos.Offset:tokenEnd(last)])
+	} else if len(old) > 0 {
+		st.Pos.Filename = old[0].Pos.Filename
+	}
+
+	toks := append([]token.Token(nil), old[:keep]...)
+	l := Resume(src, st)
+
+	delta := len(edit.Text) - (edit.End - edit.Start)
+	editEnd := edit.Start + len(edit.Text) // end of the edit in src
+	oldState := replay
+	j := keep
+	lexed := 0
+	for {
+		tok := l.NextToken()
+		toks = append(toks, tok)
+		lexed++
+		if tok.Type == token.EOF {
+			return toks, lexed
+		}
+		if tok.Pos.Offset < editEnd {
+			continue
+		}
+
+		// look for the old token at the same place after the edit
+		for j < len(old) && old[j].Pos.Offset+delta < tok.Pos.Offset {
+			oldState.track(old[j])
+			j++
+		}
+		if j == len(old) || old[j].Pos.Offset < edit.End || old[j].Pos.Offset+delta != tok.Pos.Offset {
+			continue
+		}
+		match := old[j]
+		oldState.track(match)
+		j++
+		if match.Type != tok.Type || match.Text != tok.Text || !l.sameState(oldState) {
+			continue
+		}
+
+		lineDelta := tok.Pos.Line - match.Pos.Line
+		colDelta := tok.Pos.Column - match.Pos.Column
+		for _, o := range old[j:] {
+			if o.Pos.Line == match.Pos.Line {
+				o.Pos.Column += colDelta
+			}
+			o.Pos.Offset += delta
+			o.Pos.Line += lineDelta
+			toks = append(toks, o)
+		}
+		return toks, lexed
+	}
+}
+
+// sameState reports whether the next token of l would be lexed the same as
+// the next token of o given the same input
+func (l *Lexer) sameState(o *Lexer) bool {
+	if l.prev != o.prev || len(l.stack) != len(o.stack) {
+		return false
+	}
+	for i := range l.stack {
+		if l.stack[i] != o.stack[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenEnd returns the offset just past tok and its trailing trivia
+func tokenEnd(tok token.Token) int {
+	end := tok.Pos.Offset + len(tok.Text)
+	for _, tr := range tok.Trailing {
+		end += len(tr.Text)
+	}
+	return end
+}
+
+// advance returns the position just past text when text starts at pos
+func advance(pos token.Position, text string) token.Position {
+	for _, ch := range text {
+		if ch == '\n' {
+			pos.Line++
+			pos.Column = 0
+		}
+		pos.Column++
+	}
+	pos.Offset += len(text)
+	return pos
+}
@@ -0,0 +1,116 @@
+package lexer
+
+import (
+	"b/token"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// lexAll returns every token of input up to and including EOF
+func lexAll(input string, mode Mode) []token.Token {
+	l := NewFile("test.b", input)
+	l.SetMode(mode)
+	var toks []token.Token
+	for {
+		tok := l.NextToken()
+		toks = append(toks, tok)
+		if tok.Type == token.EOF {
+			return toks
+		}
+	}
+}
+
+func applyEdit(src string, edit Edit) string {
+	return src[:edit.Start] + edit.Text + src[edit.End:]
+}
+
+// insert returns the edit that inserts text n bytes into the first sub in src
+func insert(src, sub string, n int, text string) Edit {
+	i := strings.Index(src, sub) + n
+	return Edit{i, i, text}
+}
+
+func TestResume(t *testing.T) {
+	input := "val x = [1, 2] # c\nprint(\"a #{x / 2} b\")\n"
+
+	want := lexAll(input, ScanTrivia)
+	for n := 0; n < len(want); n++ {
+		l := NewFile("test.b", input)
+		l.SetMode(ScanTrivia)
+		for i := 0; i < n; i++ {
+			l.NextToken()
+		}
+		r := Resume(input, l.State())
+		for i, tt := range want[n:] {
+			tok := r.NextToken()
+			if !reflect.DeepEqual(tok, tt) {
+				t.Fatalf("resumed after %d tokens, test[%d] wrong. expected=%+v, got=%+v", n, i, tt, tok)
+			}
+		}
+	}
+}
+
+func TestRelex(t *testing.T) {
+	input := `val x = 1..2
+fun add(a, b) {
+	return a + b # sum
+}
+### block
+comment ###
+var s = "total #{add(x, 2)} done"
+print(s / 2, /re/i)
+`
+
+	at := func(sub string) int {
+		return strings.Index(input, sub)
+	}
+
+	tests := []struct {
+		name string
+		edit Edit
+	}{
+		{"insert at start", Edit{0, 0, "  "}},
+		{"extend identifier", insert(input, "val x", 5, "yz")},
+		{"range to float", Edit{at("1..2") + 2, at("1..2") + 3, ""}},
+		{"join lines", Edit{at("\nfun"), at("\nfun") + 1, " "}},
+		{"split lines", insert(input, "return", 0, "\n")},
+		{"open block comment", insert(input, "var s", 0, "###")},
+		{"edit comment text", Edit{at("comment ###"), at("comment ###") + 7, "note"}},
+		{"edit interpolation", insert(input, "2)}", 1, " + 1")},
+		{"close string early", insert(input, "total ", 6, "\"")},
+		{"division to regex", Edit{at("s / 2") + 2, at("s / 2") + 3, ")\nprint(/"}},
+		{"delete everything", Edit{0, len(input), ""}},
+		{"append at end", Edit{len(input), len(input), "x"}},
+	}
+
+	for _, mode := range []Mode{0, ScanComments, ScanTrivia} {
+		old := lexAll(input, mode)
+		for _, tt := range tests {
+			src := applyEdit(input, tt.edit)
+			want := lexAll(src, mode)
+			got := Relex(old, src, tt.edit, mode)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("mode %d, %s: tokens wrong.\nexpected=%+v\ngot=%+v", mode, tt.name, want, got)
+			}
+		}
+	}
+}
+
+func TestRelexReusesTokens(t *testing.T) {
+	line := "var x = add(1, 2) # comment\n"
+	input := strings.Repeat(line, 100)
+	old := lexAll(input, ScanTrivia)
+
+	start := 50*len(line) + len("var x = add(")
+	edit := Edit{start, start + 1, "42"}
+	src := applyEdit(input, edit)
+
+	got, lexed := relex(old, src, edit, ScanTrivia)
+	if want := lexAll(src, ScanTrivia); !reflect.DeepEqual(got, want) {
+		t.Fatalf("tokens wrong.\nexpected=%+v\ngot=%+v", want, got)
+	}
+	if lexed > 10 {
+		t.Fatalf("too many tokens lexed again. got=%d", lexed)
+	}
+}
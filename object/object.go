@@ -0,0 +1,43 @@
+package object
+
+import "sync"
+
+type ObjectType string
+
+const (
+	SYMBOL_OBJ = "SYMBOL"
+)
+
+// Object is the interface every runtime value implements
+type Object interface {
+	Type() ObjectType
+	Inspect() string
+}
+
+// Symbol is the runtime value of a :name literal. Symbols are interned so
+// there is only ever one *Symbol for a name, which lets them be compared
+// and used as object keys by pointer instead of by string
+type Symbol struct {
+	Name string
+}
+
+func (s *Symbol) Type() ObjectType { return SYMBOL_OBJ }
+func (s *Symbol) Inspect() string  { return ":" + s.Name }
+
+var (
+	symbolsMu sync.Mutex
+	symbols   = map[string]*Symbol{}
+)
+
+// Intern returns the symbol for name, creating it the first time the name is seen
+func Intern(name string) *Symbol {
+	symbolsMu.Lock()
+	defer symbolsMu.Unlock()
+
+	if sym, ok := symbols[name]; ok {
+		return sym
+	}
+	sym := &Symbol{Name: name}
+	symbols[name] = sym
+	return sym
+}
@@ -0,0 +1,21 @@
+package object
+
+import "testing"
+
+func TestInternSymbols(t *testing.T) {
+	name1 := Intern("name")
+	name2 := Intern("name")
+	age := Intern("age")
+
+	if name1 != name2 {
+		t.Errorf("symbols with the same name are not the same value")
+	}
+
+	if name1 == age {
+		t.Errorf("symbols with different names are the same value")
+	}
+
+	if name1.Inspect() != ":name" {
+		t.Errorf("name1.Inspect() wrong. expected=%q, got=%q", ":name", name1.Inspect())
+	}
+}
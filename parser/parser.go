@@ -6,22 +6,75 @@ import (
 	"b/token"
 	"fmt"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
-// TODO: Need to add precedence for remaining operators
-// this includes, boolean stuff (and or not), bitwise (& | ~ ^),
-//
+// The precedence levels from loosest to tightest binding. `not` sits
+// between the word and the symbol forms of the boolean operators so that
+// `not a and b` is `(not a) and b` while `not a || b` is `not (a || b)`
 const (
 	_           int = iota
 	LOWEST          // the lowest precedence possible
-	EQUALS          // this includes any assignment operators (==)
-	LESSGREATER     // > or <
-	SUM             // +
-	PRODUCT         // *
-	PREFIX          // -x or !x (note that ! will probably become `not` for boolean stuff)
+	OR              // or
+	AND             // and
+	NOT             // not x
+	LOGICALOR       // ||
+	LOGICALAND      // &&
+	EQUALS          // == or !=
+	LESSGREATER     // > or < or >= or <=
+	IN              // in or is
+	RANGE           // .. or ..<, binds tighter than in so x in 1..10 is x in (1..10)
+	BITOR           // |
+	BITXOR          // ^
+	BITAND          // &
+	SHIFT           // << or >>
+	SUM             // + or -
+	PRODUCT         // * or / or // or %
+	PREFIX          // -x or !x
+	POWER           // **, binds tighter than prefix so -x**2 is -(x**2)
 	CALL            // x() or x(y)
+	INDEX           // xs[i] or xs[i:j] or x.field
 )
 
+var precedences = map[token.TokenType]int{
+	token.OR:        OR,
+	token.AND:       AND,
+	token.LOR:       LOGICALOR,
+	token.LAND:      LOGICALAND,
+	token.EQ:        EQUALS,
+	token.NEQ:       EQUALS,
+	token.LT:        LESSGREATER,
+	token.GT:        LESSGREATER,
+	token.LTE:       LESSGREATER,
+	token.GTE:       LESSGREATER,
+	token.IN:        IN,
+	token.IS:        IN,
+	token.DOTDOT:    RANGE,
+	token.DOTDOTLT:  RANGE,
+	token.PIPE:      BITOR,
+	token.HAT:       BITXOR,
+	token.AMPERSAND: BITAND,
+	token.BITLS:     SHIFT,
+	token.BITRS:     SHIFT,
+	token.PLUS:      SUM,
+	token.MINUS:     SUM,
+	token.ASTERISK:  PRODUCT,
+	token.FSLASH:    PRODUCT,
+	token.FLOORDIV:  PRODUCT,
+	token.PERCENT:   PRODUCT,
+	token.POW:       POWER,
+	token.LPAREN:    CALL,
+	token.LBRACKET:  INDEX,
+	token.DOT:       INDEX,
+}
+
+// rightAssoc holds the binary operators that group from the right, so
+// 2 ** 3 ** 2 is 2 ** (3 ** 2)
+var rightAssoc = map[token.TokenType]bool{
+	token.POW: true,
+}
+
 type (
 	prefixParseFn func() ast.Expression
 	infixParseFn  func(ast.Expression) ast.Expression
@@ -32,7 +85,8 @@ type (
 type Parser struct {
 	l *lexer.Lexer
 
-	errors []string
+	errors    []string
+	lexErrors int // how many of the lexer's errors have been copied into errors
 
 	curToken  token.Token
 	peekToken token.Token
@@ -46,6 +100,7 @@ func New(l *lexer.Lexer) *Parser {
 	p := &Parser{l: l,
 		errors:         []string{},
 		prefixParseFns: make(map[token.TokenType]prefixParseFn),
+		infixParseFns:  make(map[token.TokenType]infixParseFn),
 	}
 
 	// Read two tokens so curToken and peekToken are both set
@@ -53,7 +108,28 @@ func New(l *lexer.Lexer) *Parser {
 	p.nextToken()
 
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
-	p.registerPrefix(token.NUM, p.parseNumberLiteral)
+	p.registerPrefix(token.INTLIT, p.parseNumberLiteral)
+	p.registerPrefix(token.FLOATLIT, p.parseNumberLiteral)
+	p.registerPrefix(token.STRINGLIT, p.parseStringLiteral)
+	p.registerPrefix(token.CHARLIT, p.parseCharLiteral)
+	p.registerPrefix(token.TRUE, p.parseBooleanLiteral)
+	p.registerPrefix(token.FALSE, p.parseBooleanLiteral)
+	p.registerPrefix(token.SYMBOL, p.parseSymbolLiteral)
+	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.PLUS, p.parsePrefixExpression)
+	p.registerPrefix(token.BANG, p.parsePrefixExpression)
+	p.registerPrefix(token.NOT, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
+	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
+	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.IF, p.parseIfExpression)
+
+	for tokenType := range precedences {
+		p.registerInfix(tokenType, p.parseInfixExpression)
+	}
+	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
 
 	return p
 }
@@ -76,13 +152,30 @@ func (p *Parser) Errors() []string {
 }
 
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be `%s`. got %s instead", t, p.peekToken.Type)
+	msg := fmt.Sprintf("%s: expected next token to be `%s`. got %s instead", p.peekToken.Pos, t, p.peekToken.Type)
+	p.errors = append(p.errors, msg)
+}
+
+func (p *Parser) noPrefixParseFnError(tok token.Token) {
+	if tok.Type == token.ILLEGAL {
+		// the lexer has already said what is wrong with it
+		return
+	}
+	msg := fmt.Sprintf("%s: no prefix parse function for %s found", tok.Pos, tok.Type)
 	p.errors = append(p.errors, msg)
 }
 
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	// anything the lexer complained about while reading the token is
+	// reported along with the parser's own errors
+	lexErrors := p.l.Errors()
+	for _, err := range lexErrors[p.lexErrors:] {
+		p.errors = append(p.errors, err.Error())
+	}
+	p.lexErrors = len(lexErrors)
 }
 
 // ParseProgram continually calls nextToken on the parser and
@@ -102,14 +195,34 @@ func (p *Parser) ParseProgram() *ast.Program {
 	return program
 }
 
+// parseStatement returns an untyped nil for a statement that failed to
+// parse, a nil *ast.VarStatement in the interface would not compare equal
+// to nil
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.VAR:
-		return p.parseVarStatement()
+		if stmt := p.parseVarStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	case token.VAL:
-		return p.parseValStatement()
+		if stmt := p.parseValStatement(); stmt != nil {
+			return stmt
+		}
+		return nil
 	case token.RETURN:
-		return p.parseReturnStatment()
+		if stmt := p.parseReturnStatment(); stmt != nil {
+			return stmt
+		}
+		return nil
+	case token.SEMICOLON:
+		// an empty statement
+		return nil
+	case token.FUNCTION:
+		if p.peekTokenIs(token.IDENT) {
+			return p.parseFunctionStatement()
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -127,16 +240,298 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	return stmt
 }
 
+// parseFunctionStatement parses a named function at the start of a
+// statement. When the function is used straight away, ie. called, the
+// statement is just an expression statement
+func (p *Parser) parseFunctionStatement() ast.Statement {
+	stmt := p.parseExpressionStatement()
+
+	fn, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		return stmt
+	}
+	return &ast.FunctionStatement{Token: fn.Token, Name: fn.Name, Function: fn}
+}
+
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	block := &ast.BlockStatement{Token: p.curToken}
+	block.Statements = []ast.Statement{}
+
+	p.nextToken()
+
+	// closed is set once a `;` has been written after the last statement,
+	// the ones put in at the end of a line do not count
+	closed := false
+	for !p.curTokenIs(token.RBRACE) {
+		if p.curTokenIs(token.EOF) {
+			msg := fmt.Sprintf("%s: expected `}` to close the block opened at %s", p.curToken.Pos, block.Token.Pos)
+			p.errors = append(p.errors, msg)
+			break
+		}
+		stmt := p.parseStatement()
+		if stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+			closed = false
+		}
+		if p.curTokenIs(token.SEMICOLON) && p.curToken.Literal == ";" {
+			closed = true
+		}
+		p.nextToken()
+	}
+
+	if n := len(block.Statements); n > 0 && !closed {
+		_, block.Open = block.Statements[n-1].(*ast.ExpressionStatement)
+	}
+
+	return block
+}
+
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
+		p.noPrefixParseFnError(p.curToken)
 		return nil
 	}
-
 	leftExp := prefix()
+
+	// once a part failed to parse its error is recorded and there is nothing
+	// left to apply the rest of the operators to
+	for leftExp != nil && !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+		infix := p.infixParseFns[p.peekToken.Type]
+		if infix == nil {
+			return leftExp
+		}
+
+		p.nextToken()
+
+		leftExp = infix(leftExp)
+	}
+
 	return leftExp
 }
 
+func (p *Parser) parsePrefixExpression() ast.Expression {
+	expression := &ast.PrefixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+	}
+
+	// `not` takes a whole comparison, the symbol operators only take the
+	// operand right after them
+	precedence := PREFIX
+	if p.curTokenIs(token.NOT) {
+		precedence = NOT
+	}
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+	if expression.Right == nil {
+		return nil
+	}
+
+	return expression
+}
+
+func (p *Parser) parseGroupedExpression() ast.Expression {
+	p.nextToken()
+
+	exp := p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return exp
+}
+
+func (p *Parser) parseIfExpression() ast.Expression {
+	expression := &ast.IfExpression{Token: p.curToken}
+
+	expression.Condition, expression.Consequence = p.parseCondBlock()
+	if expression.Consequence == nil {
+		return nil
+	}
+
+	for p.peekTokenIs(token.ELIF) || p.peekTokenIs(token.ELSE) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELSE) && !p.peekTokenIs(token.IF) {
+			if !p.expectPeek(token.LBRACE) {
+				return nil
+			}
+			expression.Alternative = p.parseBlockStatement()
+			break
+		}
+
+		if p.curTokenIs(token.ELSE) {
+			// else if is the same as elif
+			p.nextToken()
+		}
+		elif := &ast.ElifClause{Token: p.curToken}
+		elif.Condition, elif.Consequence = p.parseCondBlock()
+		if elif.Consequence == nil {
+			return nil
+		}
+		expression.Elifs = append(expression.Elifs, elif)
+	}
+
+	return expression
+}
+
+// parseCondBlock parses the `condition { ... }` after if or elif
+func (p *Parser) parseCondBlock() (ast.Expression, *ast.BlockStatement) {
+	p.nextToken()
+	condition := p.parseExpression(LOWEST)
+
+	if condition == nil || !p.expectPeek(token.LBRACE) {
+		return nil, nil
+	}
+
+	return condition, p.parseBlockStatement()
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	lit := &ast.FunctionLiteral{Token: p.curToken}
+
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		lit.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	lit.Parameters = p.parseParameters()
+	if lit.Parameters == nil {
+		return nil
+	}
+
+	// the result is either a list of named results or a single type
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		lit.NamedResults = p.parseParameters()
+		if lit.NamedResults == nil {
+			return nil
+		}
+	} else {
+		result, ok := p.parseAnnotation()
+		if !ok {
+			return nil
+		}
+		lit.Result = result
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	lit.Body = p.parseBlockStatement()
+
+	return lit
+}
+
+// parseParameters parses a parenthesized parameter list, the current token
+// is the opening paren. Parameters without a type share the type of the
+// next one like in Go, so `x, y int` makes both ints. It returns nil on
+// a syntax error, a list with no parameters is empty but not nil
+func (p *Parser) parseParameters() []*ast.Parameter {
+	params := []*ast.Parameter{}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+
+		param := p.parseParameter()
+		if param == nil {
+			return nil
+		}
+		params = append(params, param)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	var typ ast.TypeExpr
+	for i := len(params) - 1; i >= 0; i-- {
+		switch {
+		case params[i].Variadic:
+			typ = nil
+		case params[i].Type != nil:
+			typ = params[i].Type
+		case params[i].Default == nil:
+			params[i].Type = typ
+		}
+	}
+
+	return params
+}
+
+// parseParameter parses `[val] name [:] [...][type] [= default]`
+func (p *Parser) parseParameter() *ast.Parameter {
+	param := &ast.Parameter{Token: p.curToken}
+
+	if p.curTokenIs(token.VAL) {
+		param.Immutable = true
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(token.IDENT) {
+		msg := fmt.Sprintf("%s: expected a parameter name. got %s instead", p.curToken.Pos, p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	param.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	colon := p.peekTokenIs(token.COLON)
+	if colon {
+		p.nextToken()
+	}
+	if p.peekTokenIs(token.DOTDOTDOT) {
+		param.Variadic = true
+		p.nextToken()
+	}
+	if colon || param.Variadic || startsType(p.peekToken.Type) {
+		p.nextToken()
+		param.Type = p.parseType()
+		if param.Type == nil {
+			return nil
+		}
+	}
+
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+		param.Default = p.parseExpression(LOWEST)
+	}
+
+	return param
+}
+
+func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
+	expression := &ast.InfixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+
+	precedence := p.curPrecedence()
+	if rightAssoc[p.curToken.Type] {
+		precedence--
+	}
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+	if expression.Right == nil {
+		return nil
+	}
+
+	return expression
+}
+
 func (p *Parser) parseVarStatement() *ast.VarStatement {
 	stmt := &ast.VarStatement{Token: p.curToken}
 
@@ -146,11 +541,29 @@ func (p *Parser) parseVarStatement() *ast.VarStatement {
 
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+	typ, ok := p.parseAnnotation()
+	if !ok {
+		return nil
+	}
+	stmt.Type = typ
+
+	// a declaration with a type does not need a value
+	if stmt.Type != nil && !p.peekTokenIs(token.ASSIGN) {
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+		return stmt
+	}
+
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
 
-	for !p.curTokenIs(token.SEMICOLON) {
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -166,11 +579,29 @@ func (p *Parser) parseValStatement() *ast.ValStatement {
 
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
+	typ, ok := p.parseAnnotation()
+	if !ok {
+		return nil
+	}
+	stmt.Type = typ
+
+	// a declaration with a type does not need a value
+	if stmt.Type != nil && !p.peekTokenIs(token.ASSIGN) {
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+		return stmt
+	}
+
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
 
-	for !p.curTokenIs(token.SEMICOLON) {
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -180,11 +611,19 @@ func (p *Parser) parseValStatement() *ast.ValStatement {
 func (p *Parser) parseReturnStatment() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
+	// a bare return has nothing before the end of the statement
+	if p.peekTokenIs(token.SEMICOLON) || p.peekTokenIs(token.RBRACE) || p.peekTokenIs(token.EOF) {
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+		return stmt
+	}
+
 	p.nextToken()
 
-	// TODO Were skipping the expressions until we encounter
-	// a semicolon.
-	for !p.curTokenIs(token.SEMICOLON) {
+	stmt.ReturnValue = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -195,27 +634,198 @@ func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parseNumberLiteral picks the kind of number from the form the lexer
+// found it in, a fraction or an exponent makes it a float
 func (p *Parser) parseNumberLiteral() ast.Expression {
-	// this is the helper function which will end up properly parsing numbers
-	// for now only integers will be supported
+	if p.curTokenIs(token.FLOATLIT) {
+		return p.parseFloatLiteral()
+	}
 	return p.parseIntegerLiteral()
 }
 
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
-	//TODO: Potentially parse this ourselves to figure out which type of number it is?
-	// then this would end up being a helper that gets called first before
-	// the type of number that needs to be parsed
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+
+	digits, base := integerBase(p.curToken.Literal)
+	value, err := strconv.ParseInt(digits, base, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.numberError(err, "integer", "int64")
+	}
+	lit.Value = value
+
+	return lit
+}
+
+// integerBase splits an integer literal into its digits without the `_`
+// separators and the base they are written in. Only a 0x, 0o or 0b prefix
+// changes the base, a leading 0 on its own is still decimal
+func integerBase(lit string) (digits string, base int) {
+	base = 10
+	if len(lit) > 1 && lit[0] == '0' {
+		switch lit[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 10 {
+			lit = lit[2:]
+		}
+	}
+	return strings.Replace(lit, "_", "", -1), base
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.numberError(err, "float", "float64")
 	}
 	lit.Value = value
 
 	return lit
 }
 
+// numberError reports that the current token could not be turned into a
+// number of the given kind, goType is the type the value is held in
+func (p *Parser) numberError(err error, kind, goType string) {
+	var msg string
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		msg = fmt.Sprintf("%s: %s literal %s is out of range for %s", p.curToken.Pos, kind, p.curToken.Literal, goType)
+	} else {
+		msg = fmt.Sprintf("%s: could not parse %q as %s", p.curToken.Pos, p.curToken.Literal, kind)
+	}
+	p.errors = append(p.errors, msg)
+}
+
+func (p *Parser) parseBooleanLiteral() ast.Expression {
+	return &ast.BooleanLiteral{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal, Raw: p.curToken.Raw}
+}
+
+func (p *Parser) parseCharLiteral() ast.Expression {
+	// the lexer has already checked that the literal holds a single rune
+	value, _ := utf8.DecodeRuneInString(p.curToken.Literal)
+	return &ast.CharLiteral{Token: p.curToken, Value: value}
+}
+
+func (p *Parser) parseSymbolLiteral() ast.Expression {
+	return &ast.SymbolLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	if exp.Arguments == nil {
+		return nil
+	}
+	return exp
+}
+
+// parseExpressionList parses comma separated expressions up to end, a
+// trailing comma is allowed
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+
+	for !p.peekTokenIs(end) {
+		p.nextToken()
+		exp := p.parseExpression(LOWEST)
+		if exp == nil {
+			return nil
+		}
+		list = append(list, exp)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(end) {
+		return nil
+	}
+
+	return list
+}
+
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: object}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
+// parseIndexExpression parses xs[i] as well as the slices xs[low:high]
+// and xs[low:high:step] where any of the parts can be left out. A colon
+// followed straight away by a name is a symbol, so m[:key] indexes m by
+// the symbol :key. A slice bound that is a name needs a space after its
+// colon, as in xs[: n] or xs[:: n]
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	tok := p.curToken
+
+	var index ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		index = p.parseExpression(LOWEST)
+		if index == nil {
+			return nil
+		}
+	}
+
+	if !p.peekTokenIs(token.COLON) {
+		if !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		return &ast.IndexExpression{Token: tok, Left: left, Index: index}
+	}
+
+	slice := &ast.SliceExpression{Token: tok, Left: left, Low: index}
+	for i := 0; i < 2 && p.peekTokenIs(token.COLON); i++ {
+		p.nextToken()
+
+		var bound ast.Expression
+		if !p.peekTokenIs(token.COLON) && !p.peekTokenIs(token.RBRACKET) {
+			p.nextToken()
+			bound = p.parseExpression(LOWEST)
+		}
+
+		if i == 0 {
+			slice.High = bound
+		} else {
+			slice.Step = bound
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return slice
+}
+
+func (p *Parser) peekPrecedence() int {
+	if p, ok := precedences[p.peekToken.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+func (p *Parser) curPrecedence() int {
+	if p, ok := precedences[p.curToken.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
+
 func (p *Parser) curTokenIs(t token.TokenType) bool {
 	return p.curToken.Type == t
 }
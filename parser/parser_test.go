@@ -3,6 +3,7 @@ package parser
 import (
 	"b/ast"
 	"b/lexer"
+	"fmt"
 	"testing"
 )
 
@@ -39,10 +40,11 @@ func TestVarStatements(t *testing.T) {
 
 	tests := []struct {
 		expectedIdentifier string
+		expectedValue      interface{}
 	}{
-		{"x"},
-		{"y"},
-		{"foobar"},
+		{"x", 5},
+		{"y", 10},
+		{"foobar", 5353},
 	}
 
 	for i, tt := range tests {
@@ -50,6 +52,9 @@ func TestVarStatements(t *testing.T) {
 		if !testVarStatement(t, stmt, tt.expectedIdentifier) {
 			return
 		}
+		if !testLiteralExpression(t, statementValue(stmt), tt.expectedValue) {
+			return
+		}
 	}
 }
 
@@ -73,10 +78,11 @@ func TestValStatements(t *testing.T) {
 
 	tests := []struct {
 		expectedIdentifier string
+		expectedValue      interface{}
 	}{
-		{"x"},
-		{"y"},
-		{"foobar"},
+		{"x", 5},
+		{"y", 10},
+		{"foobar", 5353},
 	}
 
 	for i, tt := range tests {
@@ -84,6 +90,9 @@ func TestValStatements(t *testing.T) {
 		if !testValStatement(t, stmt, tt.expectedIdentifier) {
 			return
 		}
+		if !testLiteralExpression(t, statementValue(stmt), tt.expectedValue) {
+			return
+		}
 	}
 }
 
@@ -135,7 +144,9 @@ func TestReturnStatements(t *testing.T) {
 		t.Fatalf("program.Statements does not contain 3 statements. got=%d", len(program.Statements))
 	}
 
-	for _, stmt := range program.Statements {
+	expected := []int64{5, 10, 993322}
+
+	for i, stmt := range program.Statements {
 		returnStmt, ok := stmt.(*ast.ReturnStatement)
 		if !ok {
 			t.Errorf("stmt not *ast.returnStatement. got=%T", stmt)
@@ -145,7 +156,63 @@ func TestReturnStatements(t *testing.T) {
 			t.Errorf("returnStmt.TokenLiteral not 'return', got %q",
 				returnStmt.TokenLiteral())
 		}
+		testIntegerLiteral(t, returnStmt.ReturnValue, expected[i])
+	}
+}
+
+func TestStatementValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var x = 1 + 2 * 3;", "var x = (1 + (2 * 3));"},
+		{"val y = -a\nval z = true", "val y = (-a);val z = true;"},
+		{"var s = \"hi\"", `var s = "hi";`},
+		{"return a or b", "return (a or b);"},
+		{"return\nx", "return;x"},
+		{"return;", "return;"},
+		{"return", "return;"},
+		{"var x = 5 return x", "var x = 5;return x;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestStatementMissingValue(t *testing.T) {
+	l := lexer.NewFile("test.b", "var x =")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error. got=%q", errors)
+	}
+
+	expected := "test.b:1:8: no prefix parse function for EOF found"
+	if errors[0] != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errors[0])
+	}
+}
+
+// statementValue returns the expression on the right of a var or val
+func statementValue(s ast.Statement) ast.Expression {
+	switch s := s.(type) {
+	case *ast.VarStatement:
+		return s.Value
+	case *ast.ValStatement:
+		return s.Value
 	}
+	return nil
 }
 
 func TestIdentifierExpression(t *testing.T) {
@@ -207,3 +274,1363 @@ func TestNumberExpression(t *testing.T) {
 		t.Errorf("ident.TokenLiteral() not %s, got=%s", "5", literal.TokenLiteral())
 	}
 }
+
+func TestIntegerLiteralBases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"010;", 10},
+		{"0_10;", 10},
+		{"08;", 8},
+		{"09;", 9},
+		{"0;", 0},
+		{"1_000;", 1000},
+		{"0x1f;", 31},
+		{"0X_FF;", 255},
+		{"0o17;", 15},
+		{"0b1010;", 10},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("%q - exp not *ast.IntegerLiteral. got=%T", tt.input, stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("%q - literal.Value not %d. got=%d", tt.input, tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5;", 1.5},
+		{"0.25;", 0.25},
+		{"1e3;", 1000},
+		{"2.5E-1;", 0.25},
+		{"1_000.5;", 1000.5},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program does not have enough statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatment. got=%T", program.Statements[0])
+		}
+
+		if !testFloatLiteral(t, stmt.Expression, tt.expected) {
+			return
+		}
+	}
+}
+
+func TestNumberLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807;", ""},
+		{"9223372036854775808;", "test.b:1:1: integer literal 9223372036854775808 is out of range for int64"},
+		{"0xffff_ffff_ffff_ffff;", "test.b:1:1: integer literal 0xffff_ffff_ffff_ffff is out of range for int64"},
+		{"x + 1e400;", "test.b:1:5: float literal 1e400 is out of range for float64"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewFile("test.b", tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if tt.expected == "" {
+			if len(errors) != 0 {
+				t.Errorf("%q - expected no errors. got=%q", tt.input, errors)
+			}
+			continue
+		}
+		if len(errors) != 1 {
+			t.Errorf("%q - expected 1 error. got=%q", tt.input, errors)
+			continue
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("%q - wrong error message. expected=%q, got=%q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestBooleanExpression(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedBoolean bool
+	}{
+		{"true;", true},
+		{"false;", false},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		if !testBooleanLiteral(t, stmt.Expression, tt.expectedBoolean) {
+			return
+		}
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input       string
+		expected    string
+		expectedRaw bool
+		expectedStr string
+	}{
+		{`"hello world";`, "hello world", false, `"hello world"`},
+		{`"tab\there";`, "tab\there", false, `"tab\there"`},
+		{"`raw\\n`;", `raw\n`, true, "`raw\\n`"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		literal, ok := stmt.Expression.(*ast.StringLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+		}
+
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %q. got=%q", tt.expected, literal.Value)
+		}
+
+		if literal.Raw != tt.expectedRaw {
+			t.Errorf("literal.Raw not %t. got=%t", tt.expectedRaw, literal.Raw)
+		}
+
+		if literal.String() != tt.expectedStr {
+			t.Errorf("literal.String() not %s. got=%s", tt.expectedStr, literal.String())
+		}
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	input := `var x = 5;
+var = 10;`
+
+	l := lexer.NewFile("test.b", input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors. got none")
+	}
+
+	expected := "test.b:2:5: expected next token to be `IDENT`. got = instead"
+	if errors[0] != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errors[0])
+	}
+}
+
+func TestCharLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected rune
+	}{
+		{`'a';`, 'a'},
+		{`'\n';`, '\n'},
+		{`'é';`, 'é'},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program does not have enough statements. got=%d", len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatment. got=%T", program.Statements[0])
+		}
+
+		literal, ok := stmt.Expression.(*ast.CharLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.CharLiteral. got=%T", stmt.Expression)
+		}
+
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %q. got=%q", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestSymbolExpression(t *testing.T) {
+	input := ":name;"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program does not have enough statements. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatment. got=%T", program.Statements[0])
+	}
+
+	symbol, ok := stmt.Expression.(*ast.SymbolLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.SymbolLiteral. got=%T", stmt.Expression)
+	}
+
+	if symbol.Value != "name" {
+		t.Errorf("symbol.Value not %s. got=%s", "name", symbol.Value)
+	}
+
+	if symbol.String() != ":name" {
+		t.Errorf("symbol.String() not %s. got=%s", ":name", symbol.String())
+	}
+}
+
+func TestLexerErrorsAreReported(t *testing.T) {
+	input := `val x = 1;
+val y = "a\qb";`
+
+	l := lexer.NewFile("test.b", input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error. got=%d (%q)", len(errors), errors)
+	}
+
+	expected := `test.b:2:11: unknown escape sequence \q`
+	if errors[0] != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errors[0])
+	}
+}
+
+func TestParsingPrefixExpressions(t *testing.T) {
+	prefixTests := []struct {
+		input    string
+		operator string
+		value    interface{}
+	}{
+		{"-15;", "-", 15},
+		{"+15;", "+", 15},
+		{"!ok;", "!", "ok"},
+		{"!true;", "!", true},
+		{"-1.5;", "-", 1.5},
+		{"not done;", "not", "done"},
+		{"~mask;", "~", "mask"},
+	}
+
+	for _, tt := range prefixTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d", 1, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatment. got=%T", program.Statements[0])
+		}
+
+		exp, ok := stmt.Expression.(*ast.PrefixExpression)
+		if !ok {
+			t.Fatalf("stmt is not ast.PrefixExpression. got=%T", stmt.Expression)
+		}
+		if exp.Operator != tt.operator {
+			t.Fatalf("exp.Operator is not '%s'. got=%s", tt.operator, exp.Operator)
+		}
+		if !testLiteralExpression(t, exp.Right, tt.value) {
+			return
+		}
+	}
+}
+
+func TestParsingInfixExpressions(t *testing.T) {
+	infixTests := []struct {
+		input      string
+		leftValue  interface{}
+		operator   string
+		rightValue interface{}
+	}{
+		{"5 + 5;", 5, "+", 5},
+		{"5 - 5;", 5, "-", 5},
+		{"5 * 5;", 5, "*", 5},
+		{"5 / 5;", 5, "/", 5},
+		{"5 // 5;", 5, "//", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"5 ** 5;", 5, "**", 5},
+		{"5 > 5;", 5, ">", 5},
+		{"5 < 5;", 5, "<", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 == 5;", 5, "==", 5},
+		{"5 != 5;", 5, "!=", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 & 5;", 5, "&", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
+		{"true == true", true, "==", true},
+		{"true != false", true, "!=", false},
+		{"1.5 * 2.0", 1.5, "*", 2.0},
+		{"a && b;", "a", "&&", "b"},
+		{"a || b;", "a", "||", "b"},
+		{"a and b;", "a", "and", "b"},
+		{"a or b;", "a", "or", "b"},
+		{"a in b;", "a", "in", "b"},
+		{"1..10;", 1, "..", 10},
+		{"1..<10;", 1, "..<", 10},
+		{"a is b;", "a", "is", "b"},
+	}
+
+	for _, tt := range infixTests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d", 1, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatment. got=%T", program.Statements[0])
+		}
+
+		if !testInfixExpression(t, stmt.Expression, tt.leftValue, tt.operator, tt.rightValue) {
+			return
+		}
+	}
+}
+
+func TestOperatorPrecedenceParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a + b + c", "((a + b) + c)"},
+		{"a + b - c", "((a + b) - c)"},
+		{"a * b * c", "((a * b) * c)"},
+		{"a * b / c // d % e", "((((a * b) / c) // d) % e)"},
+		{"a + b * c + d / e - f", "(((a + (b * c)) + (d / e)) - f)"},
+		{"a ** b ** c", "(a ** (b ** c))"},
+		{"a * b ** c", "(a * (b ** c))"},
+		{"a << b + c", "(a << (b + c))"},
+		{"a & b << c", "(a & (b << c))"},
+		{"a | b ^ c & d", "(a | (b ^ (c & d)))"},
+		{"a in b | c", "(a in (b | c))"},
+		{"a < b in c", "(a < (b in c))"},
+		{"x in 1..10", "(x in (1 .. 10))"},
+		{"x in 1..<n + 1", "(x in (1 ..< (n + 1)))"},
+		{"a..b == c", "((a .. b) == c)"},
+		{"a | b..c & d", "((a | b) .. (c & d))"},
+		{"a..b..c", "((a .. b) .. c)"},
+		{"5 > 4 == 3 < 4", "((5 > 4) == (3 < 4))"},
+		{"5 < 4 != 3 > 4", "((5 < 4) != (3 > 4))"},
+		{"a == b && c != d", "((a == b) && (c != d))"},
+		{"a && b || c && d", "((a && b) || (c && d))"},
+		{"a || b and c || d", "((a || b) and (c || d))"},
+		{"a and b or c and d", "((a and b) or (c and d))"},
+		{"3 + 4 * 5 == 3 * 1 + 4 * 5", "((3 + (4 * 5)) == ((3 * 1) + (4 * 5)))"},
+		{"true", "true"},
+		{"3 > 5 == false", "((3 > 5) == false)"},
+		{"!(true == true)", "(!(true == true))"},
+		{"1.5 + 2 * 3.0", "(1.5 + (2 * 3.0))"},
+		{`"a" + "b"`, `("a" + "b")`},
+		{"-a * b", "((-a) * b)"},
+		{"!-a", "(!(-a))"},
+		{"~a & b", "((~a) & b)"},
+		{"-a ** 2", "(-(a ** 2))"},
+		{"a + -b", "(a + (-b))"},
+		{"+a - -b", "((+a) - (-b))"},
+		{"not a and b", "((not a) and b)"},
+		{"not a == b", "(not (a == b))"},
+		{"not a || b", "(not (a || b))"},
+		{"a or not b and c", "(a or ((not b) and c))"},
+		{"1 + (2 + 3) + 4", "((1 + (2 + 3)) + 4)"},
+		{"(5 + 5) * 2", "((5 + 5) * 2)"},
+		{"2 / (5 + 5)", "(2 / (5 + 5))"},
+		{"-(5 + 5)", "(-(5 + 5))"},
+		{"(a +\nb) * c", "((a + b) * c)"},
+		{"(a ** b) ** c", "((a ** b) ** c)"},
+		{"a + b; c * d", "(a + b)(c * d)"},
+		{"a +\nb\nc", "(a + b)c"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestNoPrefixParseFnError(t *testing.T) {
+	l := lexer.NewFile("test.b", "1 + * 2;")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors. got none")
+	}
+
+	expected := "test.b:1:5: no prefix parse function for * found"
+	if errors[0] != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errors[0])
+	}
+}
+
+func testInfixExpression(t *testing.T, exp ast.Expression, left interface{}, operator string, right interface{}) bool {
+	opExp, ok := exp.(*ast.InfixExpression)
+	if !ok {
+		t.Errorf("exp is not ast.InfixExpression. got=%T(%s)", exp, exp)
+		return false
+	}
+
+	if !testLiteralExpression(t, opExp.Left, left) {
+		return false
+	}
+
+	if opExp.Operator != operator {
+		t.Errorf("exp.Operator is not '%s'. got=%q", operator, opExp.Operator)
+		return false
+	}
+
+	if !testLiteralExpression(t, opExp.Right, right) {
+		return false
+	}
+
+	return true
+}
+
+func testLiteralExpression(t *testing.T, exp ast.Expression, expected interface{}) bool {
+	switch v := expected.(type) {
+	case int:
+		return testIntegerLiteral(t, exp, int64(v))
+	case int64:
+		return testIntegerLiteral(t, exp, v)
+	case float64:
+		return testFloatLiteral(t, exp, v)
+	case string:
+		return testIdentifier(t, exp, v)
+	case bool:
+		return testBooleanLiteral(t, exp, v)
+	}
+	t.Errorf("type of exp not handled. got=%T", exp)
+	return false
+}
+
+func testIntegerLiteral(t *testing.T, il ast.Expression, value int64) bool {
+	integ, ok := il.(*ast.IntegerLiteral)
+	if !ok {
+		t.Errorf("il not *ast.IntegerLiteral. got=%T", il)
+		return false
+	}
+
+	if integ.Value != value {
+		t.Errorf("integ.Value not %d. got=%d", value, integ.Value)
+		return false
+	}
+
+	if integ.TokenLiteral() != fmt.Sprintf("%d", value) {
+		t.Errorf("integ.TokenLiteral not %d. got=%s", value, integ.TokenLiteral())
+		return false
+	}
+
+	return true
+}
+
+func testIdentifier(t *testing.T, exp ast.Expression, value string) bool {
+	ident, ok := exp.(*ast.Identifier)
+	if !ok {
+		t.Errorf("exp not *ast.Identifier. got=%T", exp)
+		return false
+	}
+
+	if ident.Value != value {
+		t.Errorf("ident.Value not %s. got=%s", value, ident.Value)
+		return false
+	}
+
+	if ident.TokenLiteral() != value {
+		t.Errorf("ident.TokenLiteral not %s. got=%s", value, ident.TokenLiteral())
+		return false
+	}
+
+	return true
+}
+
+func testFloatLiteral(t *testing.T, exp ast.Expression, value float64) bool {
+	fl, ok := exp.(*ast.FloatLiteral)
+	if !ok {
+		t.Errorf("exp not *ast.FloatLiteral. got=%T", exp)
+		return false
+	}
+
+	if fl.Value != value {
+		t.Errorf("fl.Value not %g. got=%g", value, fl.Value)
+		return false
+	}
+
+	return true
+}
+
+func testBooleanLiteral(t *testing.T, exp ast.Expression, value bool) bool {
+	bo, ok := exp.(*ast.BooleanLiteral)
+	if !ok {
+		t.Errorf("exp not *ast.BooleanLiteral. got=%T", exp)
+		return false
+	}
+
+	if bo.Value != value {
+		t.Errorf("bo.Value not %t. got=%t", value, bo.Value)
+		return false
+	}
+
+	if bo.TokenLiteral() != fmt.Sprintf("%t", value) {
+		t.Errorf("bo.TokenLiteral not %t. got=%s", value, bo.TokenLiteral())
+		return false
+	}
+
+	return true
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var x: int = 5", "var x: int = 5;"},
+		{"var x int = 5", "var x: int = 5;"},
+		{"val x : str", "val x: str;"},
+		{"var count: u64\nval f: f32", "var count: u64;val f: f32;"},
+		{"val abc : mytype = b", "val abc: mytype = b;"},
+		{"var xs: list[int]", "var xs: list[int];"},
+		{"var m: map[str]int", "var m: map[str]int;"},
+		{"var m: dict[str]list[int]", "var m: dict[str]list[int];"},
+		{"var s: set[char]", "var s: set[char];"},
+		{"var c: chan int", "var c: chan int;"},
+		{"var c: chan<- int", "var c: chan<- int;"},
+		{"var c: <-chan list[str]", "var c: <-chan list[str];"},
+		{"var f: fun()", "var f: fun();"},
+		{"var f: fun(int, str) bool", "var f: fun(int, str) bool;"},
+		{"var f: fun(int): fun() int", "var f: fun(int) fun() int;"},
+		{"var f: fun(str, ...int)", "var f: fun(str, ...int);"},
+		{"var u: <str|int> = 1", "var u: <str|int> = 1;"},
+		{"var m: map[<str|int>]<str|int|list[int]>", "var m: map[<str|int>]<str|int|list[int]>;"},
+		{"var u: <a|<b|c>>", "var u: <a|<b|c>>;"},
+		{"var u: <a|b>= 1", "var u: <a|b> = 1;"},
+		{"var o: obj{}", "var o: obj{};"},
+		{"var o: obj{name: str, age: int}", "var o: obj{name: str, age: int};"},
+		{"var o: obj{\n\tname: str,\n\tinner: obj{\n\t\tyy: int\n\t}\n}", "var o: obj{name: str, inner: obj{yy: int}};"},
+		{"var o: obj = x", "var o: obj = x;"},
+		{"var a: any", "var a: any;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestTypeNodes(t *testing.T) {
+	l := lexer.New("var c: chan<- map[str]fun(...int) <a|b>")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.VarStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.VarStatement. got=%T", program.Statements[0])
+	}
+
+	ch, ok := stmt.Type.(*ast.ChanType)
+	if !ok {
+		t.Fatalf("stmt.Type is not ast.ChanType. got=%T", stmt.Type)
+	}
+	if ch.Dir != ast.ChanSend {
+		t.Errorf("ch.Dir is not ChanSend. got=%d", ch.Dir)
+	}
+
+	m, ok := ch.Elem.(*ast.MapType)
+	if !ok {
+		t.Fatalf("ch.Elem is not ast.MapType. got=%T", ch.Elem)
+	}
+	if key, ok := m.Key.(*ast.NamedType); !ok || key.Name != "str" {
+		t.Errorf("m.Key is not the named type str. got=%#v", m.Key)
+	}
+
+	fn, ok := m.Value.(*ast.FuncType)
+	if !ok {
+		t.Fatalf("m.Value is not ast.FuncType. got=%T", m.Value)
+	}
+	if len(fn.Params) != 1 || !fn.Variadic {
+		t.Errorf("fn should have one variadic parameter. got=%d, variadic=%t", len(fn.Params), fn.Variadic)
+	}
+
+	union, ok := fn.Result.(*ast.UnionType)
+	if !ok {
+		t.Fatalf("fn.Result is not ast.UnionType. got=%T", fn.Result)
+	}
+	if len(union.Types) != 2 {
+		t.Errorf("union should have 2 types. got=%d", len(union.Types))
+	}
+}
+
+func TestTypeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var x: 5", "test.b:1:8: expected a type. got INTLIT instead"},
+		{"var x: list[int", "test.b:1:16: expected next token to be `]`. got EOF instead"},
+		{"var x: <a|b", "test.b:1:12: expected next token to be `>`. got EOF instead"},
+		{"var x: obj{a int}", "test.b:1:14: expected next token to be `:`. got INT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewFile("test.b", tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("%q - expected parser errors. got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("%q - wrong error message. expected=%q, got=%q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	input := `fun(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T", stmt.Expression)
+	}
+
+	if function.Name != nil {
+		t.Fatalf("function literal should not have a name. got=%s", function.Name)
+	}
+
+	if len(function.Parameters) != 2 {
+		t.Fatalf("function literal parameters wrong. want 2, got=%d", len(function.Parameters))
+	}
+
+	testLiteralExpression(t, function.Parameters[0].Name, "x")
+	testLiteralExpression(t, function.Parameters[1].Name, "y")
+
+	if len(function.Body.Statements) != 1 {
+		t.Fatalf("function.Body.Statements has not 1 statements. got=%d", len(function.Body.Statements))
+	}
+
+	bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("function body stmt is not ast.ExpressionStatement. got=%T", function.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestFunctionParameterParsing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedParams []string
+	}{
+		{"fun() {}", []string{}},
+		{"fun(x) {}", []string{"x"}},
+		{"fun(x, y, z) {}", []string{"x", "y", "z"}},
+		{"fun(x : int, y : int) {}", []string{"x: int", "y: int"}},
+		{"fun(x int, y str) {}", []string{"x: int", "y: str"}},
+		{"fun(x, y int) {}", []string{"x: int", "y: int"}},
+		{"fun(a, b int, c) {}", []string{"a: int", "b: int", "c"}},
+		{"fun(args : ...int) {}", []string{"args: ...int"}},
+		{"fun(s str, args ...int) {}", []string{"s: str", "args: ...int"}},
+		{"fun(a, args ...int) {}", []string{"a", "args: ...int"}},
+		{"fun(x = 5, y: int = 1 + 2) {}", []string{"x = 5", "y: int = (1 + 2)"}},
+		{"fun(x = 5, y int) {}", []string{"x = 5", "y: int"}},
+		{"fun(val somevar: int) {}", []string{"val somevar: int"}},
+		{"fun(f fun(int) bool, m map[str]int) {}", []string{"f: fun(int) bool", "m: map[str]int"}},
+		{"fun(\n\tx int,\n\ty int,\n) {}", []string{"x: int", "y: int"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Errorf("%q - length parameters wrong. want %d, got=%d", tt.input, len(tt.expectedParams), len(function.Parameters))
+			continue
+		}
+
+		for i, param := range tt.expectedParams {
+			if function.Parameters[i].String() != param {
+				t.Errorf("%q - parameter %d wrong. want %q, got=%q", tt.input, i, param, function.Parameters[i].String())
+			}
+		}
+	}
+}
+
+func TestFunctionResultParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fun() {}", "fun() {}"},
+		{"fun(x int, y int) int { return x + y }", "fun(x: int, y: int) int {return (x + y);}"},
+		{"fun(x : int, y : int) : int { return x + y }", "fun(x: int, y: int) int {return (x + y);}"},
+		{"fun() : int { 1214 + 802520 * 248}", "fun() int {(1214 + (802520 * 248))}"},
+		{"fun() mytype { return }", "fun() mytype {return;}"},
+		{"fun() list[int] {}", "fun() list[int] {}"},
+		{"fun() (test_item mytype) { return }", "fun() (test_item: mytype) {return;}"},
+		{"fun() (a, b int) {}", "fun() (a: int, b: int) {}"},
+		{"val mytest = fun(x : int, y : int) : int { return x + y }", "val mytest = fun(x: int, y: int) int {return (x + y);};"},
+		{"val f = fun(name str) {\n\tname\n\treturn\n}", "val f = fun(name: str) {namereturn;};"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestFunctionStatement(t *testing.T) {
+	input := `fun main() {
+	val x = 5
+	return x
+}
+
+fun add(x : int, y : int) : int { return x + y }
+`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", 2, len(program.Statements))
+	}
+
+	tests := []struct {
+		expectedName  string
+		expectedStmts int
+	}{
+		{"main", 2},
+		{"add", 1},
+	}
+
+	for i, tt := range tests {
+		stmt, ok := program.Statements[i].(*ast.FunctionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[%d] is not ast.FunctionStatement. got=%T", i, program.Statements[i])
+		}
+		if stmt.Name.Value != tt.expectedName {
+			t.Errorf("stmt.Name not %s. got=%s", tt.expectedName, stmt.Name.Value)
+		}
+		if stmt.Function.Name != stmt.Name {
+			t.Errorf("stmt.Function.Name is not the statement name")
+		}
+		if len(stmt.Function.Body.Statements) != tt.expectedStmts {
+			t.Errorf("%s has wrong number of statements. want %d, got=%d", tt.expectedName, tt.expectedStmts, len(stmt.Function.Body.Statements))
+		}
+	}
+
+	expected := "fun main() {val x = 5;return x;}fun add(x: int, y: int) int {return (x + y);}"
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestFunctionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fun(1) {}", "test.b:1:5: expected a parameter name. got INTLIT instead"},
+		{"fun(x int", "test.b:1:10: expected next token to be `)`. got EOF instead"},
+		{"fun(x) 5", "test.b:1:8: expected next token to be `{`. got INTLIT instead"},
+		{"fun(x): 5 {}", "test.b:1:9: expected a type. got INTLIT instead"},
+		{"fun main() {\n\tx", "test.b:2:3: expected `}` to close the block opened at test.b:1:12"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewFile("test.b", tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("%q - expected parser errors. got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("%q - wrong error message. expected=%q, got=%q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestMissingOperands(t *testing.T) {
+	tests := []string{
+		"-",
+		"1 +",
+		"not",
+		"!-",
+		"1 + -",
+		"2 ** -",
+		"(1 +)",
+		"f(1, -)",
+		"xs[-]",
+		"var x = 1 *",
+		"return -",
+		"if - { 1 }",
+		"if x { 1 } elif 1 + { 2 }",
+		"var x : = 1",
+		"val y : list[ = 2",
+		"fun f() { var x : }",
+		"if a { val }",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q - expected parser errors. got none", input)
+		}
+		// a node with a missing part used to panic here
+		_ = program.String()
+	}
+}
+
+func TestCallExpressionParsing(t *testing.T) {
+	input := "add(1, 2 * 3, 4 + 5);"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, exp.Function, "add") {
+		return
+	}
+
+	if len(exp.Arguments) != 3 {
+		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+	}
+
+	testLiteralExpression(t, exp.Arguments[0], 1)
+	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
+	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
+}
+
+func TestMemberExpressionParsing(t *testing.T) {
+	input := "user.field.sub"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	outer, ok := stmt.Expression.(*ast.MemberExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.MemberExpression. got=%T", stmt.Expression)
+	}
+	if outer.Property.Value != "sub" {
+		t.Errorf("outer.Property not %s. got=%s", "sub", outer.Property.Value)
+	}
+
+	inner, ok := outer.Object.(*ast.MemberExpression)
+	if !ok {
+		t.Fatalf("outer.Object not *ast.MemberExpression. got=%T", outer.Object)
+	}
+	testIdentifier(t, inner.Object, "user")
+	if inner.Property.Value != "field" {
+		t.Errorf("inner.Property not %s. got=%s", "field", inner.Property.Value)
+	}
+}
+
+func TestMemberKeywordProperty(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"xs.map(f)", "(xs.map)(f)"},
+		{"x.type", "(x.type)"},
+		{"x.test(s)", "(x.test)(s)"},
+		{"x.i8.set.if", "(((x.i8).set).if)"},
+		{"x.type\n-1", "(x.type)(-1)"},
+		{"x.type / 2", "((x.type) / 2)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestFailedPostfixExpressions(t *testing.T) {
+	tests := []string{
+		"f(",
+		"f(1, 2",
+		"f(1, ])",
+		"user.",
+		"user.field.5",
+		"f(1).",
+		"xs[1",
+		"user.(x)[0]",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q - expected parser errors. got none", input)
+			continue
+		}
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Errorf("%q - stmt is not ast.ExpressionStatement. got=%T", input, program.Statements[0])
+			continue
+		}
+		if stmt.Expression != nil {
+			t.Errorf("%q - expected no expression. got=%q", input, stmt.Expression.String())
+		}
+	}
+}
+
+func TestIndexExpressionParsing(t *testing.T) {
+	input := "myArray[1 + 1]"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	indexExp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+
+	if !testIdentifier(t, indexExp.Left, "myArray") {
+		return
+	}
+
+	if !testInfixExpression(t, indexExp.Index, 1, "+", 1) {
+		return
+	}
+}
+
+func TestSymbolIndexParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		slice    bool
+	}{
+		// a colon followed straight away by a name is a symbol, not a slice
+		{"m[:key]", "(m[:key])", false},
+		{"xs[:n]", "(xs[:n])", false},
+		{"m[:key][:type]", "((m[:key])[:type])", false},
+		{"xs[: n]", "(xs[:n])", true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if tt.slice {
+			if _, ok := stmt.Expression.(*ast.SliceExpression); !ok {
+				t.Fatalf("%q - exp not *ast.SliceExpression. got=%T", tt.input, stmt.Expression)
+			}
+		} else {
+			exp, ok := stmt.Expression.(*ast.IndexExpression)
+			if !ok {
+				t.Fatalf("%q - exp not *ast.IndexExpression. got=%T", tt.input, stmt.Expression)
+			}
+			if _, ok := exp.Index.(*ast.SymbolLiteral); !ok {
+				t.Errorf("%q - exp.Index not *ast.SymbolLiteral. got=%T", tt.input, exp.Index)
+			}
+		}
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestSliceExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input string
+		low   interface{}
+		high  interface{}
+		step  interface{}
+	}{
+		{"xs[1:10:2]", 1, 10, 2},
+		{"xs[1:10]", 1, 10, nil},
+		{"xs[1:]", 1, nil, nil},
+		{"xs[:10]", nil, 10, nil},
+		{"xs[:]", nil, nil, nil},
+		{"xs[::2]", nil, nil, 2},
+		{"xs[i:j]", "i", "j", nil},
+		{"xs[: n]", nil, "n", nil},
+		{"xs[:: n]", nil, nil, "n"},
+		{"xs[: n:2]", nil, "n", 2},
+		{"xs[i:: n]", "i", nil, "n"},
+		{"xs[: true]", nil, true, nil},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		slice, ok := stmt.Expression.(*ast.SliceExpression)
+		if !ok {
+			t.Fatalf("%q - exp not *ast.SliceExpression. got=%T", tt.input, stmt.Expression)
+		}
+
+		testIdentifier(t, slice.Left, "xs")
+		testSliceBound(t, tt.input, "low", slice.Low, tt.low)
+		testSliceBound(t, tt.input, "high", slice.High, tt.high)
+		testSliceBound(t, tt.input, "step", slice.Step, tt.step)
+	}
+}
+
+func testSliceBound(t *testing.T, input, name string, exp ast.Expression, expected interface{}) {
+	if expected == nil {
+		if exp != nil {
+			t.Errorf("%q - %s should be left out. got=%s", input, name, exp)
+		}
+		return
+	}
+	if exp == nil {
+		t.Errorf("%q - %s is missing", input, name)
+		return
+	}
+	testLiteralExpression(t, exp, expected)
+}
+
+func TestPostfixPrecedenceParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a + add(b * c) + d", "((a + add((b * c))) + d)"},
+		{"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))", "add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))"},
+		{"add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))"},
+		{"add()", "add()"},
+		{"add(\n\t1,\n\t2,\n)", "add(1, 2)"},
+		{"-f(x)", "(-f(x))"},
+		{"-xs[0] ** 2", "(-((xs[0]) ** 2))"},
+		{"not a.ok", "(not (a.ok))"},
+		{"a * b[2] * c", "((a * (b[2])) * c)"},
+		{"add(a * b[2], b[1], 2 * c[1])", "add((a * (b[2])), (b[1]), (2 * (c[1])))"},
+		{"user.method(1).field[0]", "(((user.method)(1).field)[0])"},
+		{"xs[1:n + 1]", "(xs[1:(n + 1)])"},
+		{"xs[: n + 1]", "(xs[:(n + 1)])"},
+		{"f(x)(y)", "f(x)(y)"},
+		{"fun(){ 1 }()", "fun() {1}()"},
+		{"fun sum(x : int, y : int) : int { return x + y }(1, 4)", "fun sum(x: int, y: int) int {return (x + y);}(1, 4)"},
+		// the inline call in b.b
+		{"fun sum(x : int, y : int) : int { return x + y }() # Call a function inline", "fun sum(x: int, y: int) int {return (x + y);}()"},
+		{"val xa = mytest(1,4) + fun() : int { 1214 + 802520 * 248}", "val xa = (mytest(1, 4) + fun() int {(1214 + (802520 * 248))});"},
+		{"println(\"hi\")\nprintln(x)", "println(\"hi\")println(x)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+}
+
+func TestIfExpression(t *testing.T) {
+	input := `if (x < y) { x }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
+	}
+
+	if !testInfixExpression(t, exp.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(exp.Consequence.Statements) != 1 {
+		t.Errorf("consequence is not 1 statements. got=%d", len(exp.Consequence.Statements))
+	}
+
+	consequence, ok := exp.Consequence.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T", exp.Consequence.Statements[0])
+	}
+
+	if !testIdentifier(t, consequence.Expression, "x") {
+		return
+	}
+
+	if len(exp.Elifs) != 0 {
+		t.Errorf("exp.Elifs was not empty. got=%d", len(exp.Elifs))
+	}
+
+	if exp.Alternative != nil {
+		t.Errorf("exp.Alternative was not nil. got=%+v", exp.Alternative)
+	}
+}
+
+func TestIfElifElseExpression(t *testing.T) {
+	input := `val sign = if x < 0 {
+	-1
+} elif x == 0 {
+	0
+} else if x < 10 {
+	1
+} else {
+	2
+}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d", 1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ValStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ValStatement. got=%T", program.Statements[0])
+	}
+
+	exp, ok := stmt.Value.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.IfExpression. got=%T", stmt.Value)
+	}
+
+	testInfixExpression(t, exp.Condition, "x", "<", 0)
+
+	if len(exp.Elifs) != 2 {
+		t.Fatalf("exp.Elifs does not contain 2 clauses. got=%d", len(exp.Elifs))
+	}
+	testInfixExpression(t, exp.Elifs[0].Condition, "x", "==", 0)
+	testIntegerLiteral(t, exp.Elifs[0].Consequence.Value(), 0)
+	testInfixExpression(t, exp.Elifs[1].Condition, "x", "<", 10)
+	testIntegerLiteral(t, exp.Elifs[1].Consequence.Value(), 1)
+
+	if exp.Alternative == nil {
+		t.Fatalf("exp.Alternative is nil")
+	}
+	testIntegerLiteral(t, exp.Alternative.Value(), 2)
+
+	expected := "val sign = if (x < 0) {(-1)} elif (x == 0) {0} elif (x < 10) {1} else {2};"
+	if program.String() != expected {
+		t.Errorf("program.String() wrong. expected=%q, got=%q", expected, program.String())
+	}
+}
+
+func TestBlockOpenClosed(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedOpen bool
+	}{
+		{"if x { 1 }", true},
+		{"if x { 1; }", false},
+		{"if x {\n\t1\n}", true},
+		{"if x {\n\t1;\n}", false},
+		{"if x {\n\t1 # the value\n}", true},
+		{"if x { a; b }", true},
+		{"if x { a\n\t;\n}", false},
+		{"if x { }", false},
+		{"if x { return 1 }", false},
+		{"if x { val y = 1 }", false},
+		{"if x { if y { 1 } }", true},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.IfExpression)
+		if !ok {
+			t.Fatalf("%q - exp not *ast.IfExpression. got=%T", tt.input, stmt.Expression)
+		}
+
+		block := exp.Consequence
+		if block.Open != tt.expectedOpen {
+			t.Errorf("%q - block.Open wrong. expected=%t, got=%t", tt.input, tt.expectedOpen, block.Open)
+		}
+		if (block.Value() != nil) != tt.expectedOpen {
+			t.Errorf("%q - block.Value() wrong. got=%v", tt.input, block.Value())
+		}
+	}
+}
+
+func TestFunctionBodyOpen(t *testing.T) {
+	input := `fun add(x int, y int) int { x + y }
+fun log(msg str) { println(msg); }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	tests := []bool{true, false}
+	for i, expectedOpen := range tests {
+		stmt := program.Statements[i].(*ast.FunctionStatement)
+		if stmt.Function.Body.Open != expectedOpen {
+			t.Errorf("%s - body.Open wrong. expected=%t, got=%t", stmt.Name, expectedOpen, stmt.Function.Body.Open)
+		}
+	}
+}
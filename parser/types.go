@@ -0,0 +1,266 @@
+package parser
+
+import (
+	"b/ast"
+	"b/token"
+	"fmt"
+)
+
+// startsType reports whether a token of type t can be the first token of
+// a type
+func startsType(t token.TokenType) bool {
+	switch t {
+	case token.IDENT, token.FUNCTION, token.LT, token.LARROW:
+		return true
+	}
+	return token.IsTypeKeyword(t)
+}
+
+// parseAnnotation parses the optional type after a name or after the
+// parameters of a function, written either as `x: int` or as `x int`. ok
+// is false when there was an annotation but it could not be parsed
+func (p *Parser) parseAnnotation() (typ ast.TypeExpr, ok bool) {
+	switch {
+	case p.peekTokenIs(token.COLON):
+		p.nextToken()
+	case startsType(p.peekToken.Type):
+	default:
+		return nil, true
+	}
+	p.nextToken()
+
+	typ = p.parseType()
+	return typ, typ != nil
+}
+
+// parseType parses the type that starts at the current token, it leaves
+// the last token of the type as the current token
+func (p *Parser) parseType() ast.TypeExpr {
+	switch p.curToken.Type {
+	case token.LIST:
+		return p.parseListType()
+	case token.SET:
+		return p.parseSetType()
+	case token.MAP:
+		return p.parseMapType()
+	case token.CHANNEL, token.LARROW:
+		return p.parseChanType()
+	case token.FUNCTION:
+		return p.parseFuncType()
+	case token.LT:
+		return p.parseUnionType()
+	case token.OBJECT:
+		if p.peekTokenIs(token.LBRACE) {
+			return p.parseObjType()
+		}
+	case token.IDENT:
+		if p.curToken.Literal == "dict" && p.peekTokenIs(token.LBRACKET) {
+			return p.parseMapType()
+		}
+	default:
+		if !token.IsTypeKeyword(p.curToken.Type) {
+			msg := fmt.Sprintf("%s: expected a type. got %s instead", p.curToken.Pos, p.curToken.Type)
+			p.errors = append(p.errors, msg)
+			return nil
+		}
+	}
+	return &ast.NamedType{Token: p.curToken, Name: p.curToken.Literal}
+}
+
+// parseElemType parses the `[T]` after list or set
+func (p *Parser) parseElemType() ast.TypeExpr {
+	if !p.expectPeek(token.LBRACKET) {
+		return nil
+	}
+	p.nextToken()
+
+	elem := p.parseType()
+	if elem == nil || !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return elem
+}
+
+func (p *Parser) parseListType() ast.TypeExpr {
+	typ := &ast.ListType{Token: p.curToken}
+
+	typ.Elem = p.parseElemType()
+	if typ.Elem == nil {
+		return nil
+	}
+	return typ
+}
+
+func (p *Parser) parseSetType() ast.TypeExpr {
+	typ := &ast.SetType{Token: p.curToken}
+
+	typ.Elem = p.parseElemType()
+	if typ.Elem == nil {
+		return nil
+	}
+	return typ
+}
+
+func (p *Parser) parseMapType() ast.TypeExpr {
+	typ := &ast.MapType{Token: p.curToken}
+
+	typ.Key = p.parseElemType()
+	if typ.Key == nil {
+		return nil
+	}
+	p.nextToken()
+
+	typ.Value = p.parseType()
+	if typ.Value == nil {
+		return nil
+	}
+	return typ
+}
+
+func (p *Parser) parseChanType() ast.TypeExpr {
+	typ := &ast.ChanType{Token: p.curToken, Dir: ast.ChanBoth}
+
+	if p.curTokenIs(token.LARROW) {
+		typ.Dir = ast.ChanRecv
+		if !p.expectPeek(token.CHANNEL) {
+			return nil
+		}
+	} else if p.peekTokenIs(token.LARROW) {
+		typ.Dir = ast.ChanSend
+		p.nextToken()
+	}
+	p.nextToken()
+
+	typ.Elem = p.parseType()
+	if typ.Elem == nil {
+		return nil
+	}
+	return typ
+}
+
+func (p *Parser) parseFuncType() ast.TypeExpr {
+	typ := &ast.FuncType{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		if p.curTokenIs(token.DOTDOTDOT) {
+			typ.Variadic = true
+			p.nextToken()
+		}
+
+		param := p.parseType()
+		if param == nil {
+			return nil
+		}
+		typ.Params = append(typ.Params, param)
+
+		// only the last parameter can be variadic
+		if typ.Variadic || !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	result, ok := p.parseAnnotation()
+	if !ok {
+		return nil
+	}
+	typ.Result = result
+
+	return typ
+}
+
+func (p *Parser) parseUnionType() ast.TypeExpr {
+	typ := &ast.UnionType{Token: p.curToken}
+
+	for {
+		p.nextToken()
+
+		t := p.parseType()
+		if t == nil {
+			return nil
+		}
+		typ.Types = append(typ.Types, t)
+
+		if !p.peekTokenIs(token.PIPE) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectCloseAngle() {
+		return nil
+	}
+	return typ
+}
+
+// expectCloseAngle is expectPeek(token.GT) for the end of a union. The
+// lexer joins `>` with whatever follows it, so nested unions end in `>>`,
+// and that token is split up again here
+func (p *Parser) expectCloseAngle() bool {
+	text := p.peekToken.Text
+	if p.peekTokenIs(token.GT) || len(text) < 2 || text[0] != '>' {
+		return p.expectPeek(token.GT)
+	}
+
+	rest := p.peekToken
+	rest.Type = token.LookupOperator(text[1:])
+	rest.Literal = text[1:]
+	rest.Text = text[1:]
+	rest.Pos.Offset++
+	rest.Pos.Column++
+
+	p.curToken = p.peekToken
+	p.curToken.Type = token.GT
+	p.curToken.Literal = ">"
+	p.curToken.Text = ">"
+	p.curToken.Trailing = nil
+	rest.Leading = nil
+	p.peekToken = rest
+	return true
+}
+
+func (p *Parser) parseObjType() ast.TypeExpr {
+	typ := &ast.ObjType{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field := &ast.ObjField{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+
+		field.Type = p.parseType()
+		if field.Type == nil {
+			return nil
+		}
+		typ.Fields = append(typ.Fields, field)
+
+		// fields are split up by commas or newlines
+		if !p.peekTokenIs(token.COMMA) && !p.peekTokenIs(token.SEMICOLON) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return typ
+}
@@ -1,20 +1,117 @@
 package token
 
+import (
+	"fmt"
+	"strings"
+)
+
 type TokenType string
 
+// Position describes where a token starts in the source. Line and Column
+// are 1-based and Offset is the 0-based byte offset into the input
+type Position struct {
+	Filename string
+	Offset   int
+	Line     int
+	Column   int
+}
+
+// IsValid reports whether the position has been set by the lexer
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position in the form file:line:column, the filename
+// is left off when it is empty
+func (p Position) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
 type Token struct {
 	Type    TokenType
-	Literal string
+	Literal string // the value of the token, for strings the escapes are already decoded
+	Text    string // the exact source text the token was read from
+	Pos     Position
+	Raw     bool // set on backtick string literals which have no escape processing
+
+	// Leading and Trailing are only filled in when the lexer keeps trivia.
+	// Trailing holds the spaces and line comment after the token up to the
+	// end of its line, Leading holds everything else since the token before
+	Leading  []Trivia
+	Trailing []Trivia
+}
+
+// TriviaKind says what a piece of trivia is made of
+type TriviaKind int
+
+const (
+	Whitespace TriviaKind = iota // spaces, tabs and carriage returns
+	Newline
+	Comment
+)
+
+// Trivia is source text that is not part of any token
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+}
+
+// Source returns the token text with its leading and trailing trivia, the
+// source of a whole file is the Source of each of its tokens in order
+func (t Token) Source() string {
+	var b strings.Builder
+	for _, tr := range t.Leading {
+		b.WriteString(tr.Text)
+	}
+	b.WriteString(t.Text)
+	for _, tr := range t.Trailing {
+		b.WriteString(tr.Text)
+	}
+	return b.String()
+}
+
+// RegexFlags returns the flags written after the closing slash of a REGEX token
+func (t Token) RegexFlags() string {
+	if t.Type != REGEX {
+		return ""
+	}
+	return t.Text[strings.LastIndex(t.Text, "/")+1:]
 }
 
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	COMMENT = "COMMENT"
 
 	// Identifiers and literals
 	IDENT     = "IDENT"
-	NUM       = "NUM"
+	INTLIT    = "INTLIT"
+	FLOATLIT  = "FLOATLIT"
 	STRINGLIT = "STRINGLIT"
+	CHARLIT   = "CHARLIT"
+	SYMBOL    = "SYMBOL" // :name, the literal is the name without the colon
+
+	// Interpolated strings are split up around each `#{}` so that
+	// "a #{x} b #{=y} c" is lexed as
+	// STRINGSTART INTERPSTART IDENT INTERPEND STRINGMID INTERPDEBUG IDENT INTERPEND STRINGEND
+	STRINGSTART = "STRINGSTART" // text before the first interpolation, includes the opening quote
+	STRINGMID   = "STRINGMID"   // text between two interpolations
+	STRINGEND   = "STRINGEND"   // text after the last interpolation, includes the closing quote
+	INTERPSTART = "#{"
+	INTERPDEBUG = "#{="
+	INTERPEND   = "INTERPEND" // the `}` that closes an interpolation
+
+	REGEX = "REGEX" // /pattern/flags, the literal is the pattern
 
 	// Operators
 	ASSIGN    = "="
@@ -28,7 +125,6 @@ const (
 	TILDE     = "~"
 	PIPE      = "|"
 	PERCENT   = "%"
-	BACKTICK  = "`"
 	QUESTION  = "?"
 
 	LT  = "<"
@@ -41,6 +137,8 @@ const (
 
 	POW        = "**"
 	FLOORDIV   = "//"
+	POWEQ      = "**="
+	FLOORDIVEQ = "//="
 	PLUSEQ     = "+="
 	MINUSEQ    = "-="
 	MULEQ      = "*="
@@ -52,12 +150,20 @@ const (
 	MODEQ      = "%="
 	BITLS      = "<<"
 	BITRS      = ">>"
+	BITLSEQ    = "<<="
+	BITRSEQ    = ">>="
 	PLUSPLUS   = "++"
 	MINUSMINUS = "--"
 
+	LAND = "&&"
+	LOR  = "||"
+
 	RARROW    = "=>"
+	ARROW     = "->"
+	LARROW    = "<-"
 	DOTDOT    = ".."
 	DOTDOTDOT = "..."
+	DOTDOTLT  = "..<"
 	POUND     = "#"
 	MLCOMMENT = "###"
 
@@ -79,28 +185,49 @@ const (
 	RBRACE = "}"
 
 	// Keywords
-	FUNCTION = "FUNCTION"
-	VAR      = "VAR"
-	VAL      = "VAL"
-	TRUE     = "TRUE"
-	FALSE    = "FALSE"
-	IF       = "IF"
-	ELSE     = "ELSE"
-	ELIF     = "ELIF"
-	RETURN   = "RETURN"
-	MACRO    = "MACRO"
+	FUNCTION  = "FUNCTION"
+	VAR       = "VAR"
+	VAL       = "VAL"
+	TRUE      = "TRUE"
+	FALSE     = "FALSE"
+	IF        = "IF"
+	ELSE      = "ELSE"
+	ELIF      = "ELIF"
+	RETURN    = "RETURN"
+	MACRO     = "MACRO"
+	MATCH     = "MATCH"
+	TYPE      = "TYPE"
+	PUB       = "PUB"
+	INTERFACE = "INTERFACE"
+	IMPORT    = "IMPORT"
+	BREAK     = "BREAK"
+	CONTINUE  = "CONTINUE"
+	YIELD     = "YIELD"
+	TEST      = "TEST"
+	DEFER     = "DEFER"
 
 	AND = "AND"
 	OR  = "OR"
 	NOT = "NOT"
+	IS  = "IS"
 
 	IN  = "IN"
 	FOR = "FOR"
 
 	// Types are keywords? this may not be correct
 	INT        = "INT"
+	I8         = "I8"
+	I16        = "I16"
+	I32        = "I32"
+	I64        = "I64"
 	UINT       = "UINT"
+	U8         = "U8"
+	U16        = "U16"
+	U32        = "U32"
+	U64        = "U64"
 	FLOAT      = "FLOAT"
+	F32        = "F32"
+	F64        = "F64"
 	STRINGTYPE = "STRING"
 	OBJECT     = "OBJ"
 	ENUM       = "ENUM"
@@ -114,36 +241,210 @@ const (
 	RUNE       = "RUNE"
 )
 
+// operators maps the text of every operator and delimiter to its type.
+// The lexer always takes the longest one that matches, so adding an
+// operator only needs a new entry here
+var operators = map[string]TokenType{
+	"=": ASSIGN,
+	"+": PLUS,
+	"-": MINUS,
+	"!": BANG,
+	"*": ASTERISK,
+	"/": FSLASH,
+	"^": HAT,
+	"&": AMPERSAND,
+	"~": TILDE,
+	"|": PIPE,
+	"%": PERCENT,
+	"?": QUESTION,
+
+	"<":  LT,
+	"<=": LTE,
+	">":  GT,
+	">=": GTE,
+	"==": EQ,
+	"!=": NEQ,
+
+	"**":  POW,
+	"//":  FLOORDIV,
+	"**=": POWEQ,
+	"//=": FLOORDIVEQ,
+	"+=":  PLUSEQ,
+	"-=":  MINUSEQ,
+	"*=":  MULEQ,
+	"/=":  DIVEQ,
+	"&=":  BITANDEQ,
+	"|=":  BITOREQ,
+	"~=":  BITNOTEQ,
+	"^=":  BITXOREQ,
+	"%=":  MODEQ,
+	"<<":  BITLS,
+	">>":  BITRS,
+	"<<=": BITLSEQ,
+	">>=": BITRSEQ,
+	"++":  PLUSPLUS,
+	"--":  MINUSMINUS,
+
+	"&&": LAND,
+	"||": LOR,
+
+	"=>":  RARROW,
+	"->":  ARROW,
+	"<-":  LARROW,
+	"..":  DOTDOT,
+	"...": DOTDOTDOT,
+	"..<": DOTDOTLT,
+
+	"[": LBRACKET,
+	"]": RBRACKET,
+	":": COLON,
+	",": COMMA,
+	";": SEMICOLON,
+	".": DOT,
+	"(": LPAREN,
+	")": RPAREN,
+	"{": LBRACE,
+	"}": RBRACE,
+}
+
+// LookupOperator returns the type of the operator spelled text, or ILLEGAL
+// when there is no such operator
+func LookupOperator(text string) TokenType {
+	if tok, ok := operators[text]; ok {
+		return tok
+	}
+	return ILLEGAL
+}
+
+// Operators returns the text of every operator and delimiter
+func Operators() []string {
+	texts := make([]string, 0, len(operators))
+	for text := range operators {
+		texts = append(texts, text)
+	}
+	return texts
+}
+
 var keywords = map[string]TokenType{
-	"fun":    FUNCTION,
-	"var":    VAR,
-	"val":    VAL,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"elif":   ELIF,
-	"return": RETURN,
-	"macro":  MACRO,
-	"and":    AND,
-	"or":     OR,
-	"not":    NOT,
-	"in":     IN,
-	"for":    FOR,
-	"int":    INT,
-	"uint":   UINT,
-	"float":  FLOAT,
-	"str":    STRINGTYPE,
-	"obj":    OBJECT,
-	"enum":   ENUM,
-	"list":   LIST,
-	"map":    MAP,
-	"chan":   CHANNEL,
-	"any":    ANY,
-	"bool":   BOOLEAN,
-	"char":   CHARACTER,
-	"rune":   RUNE,
-	"set":    SET,
+	"fun":       FUNCTION,
+	"var":       VAR,
+	"val":       VAL,
+	"true":      TRUE,
+	"false":     FALSE,
+	"if":        IF,
+	"else":      ELSE,
+	"elif":      ELIF,
+	"return":    RETURN,
+	"macro":     MACRO,
+	"match":     MATCH,
+	"type":      TYPE,
+	"pub":       PUB,
+	"interface": INTERFACE,
+	"import":    IMPORT,
+	"break":     BREAK,
+	"continue":  CONTINUE,
+	"yield":     YIELD,
+	"test":      TEST,
+	"defer":     DEFER,
+	"and":       AND,
+	"or":        OR,
+	"not":       NOT,
+	"is":        IS,
+	"in":        IN,
+	"for":       FOR,
+	"int":       INT,
+	"i8":        I8,
+	"i16":       I16,
+	"i32":       I32,
+	"i64":       I64,
+	"uint":      UINT,
+	"u8":        U8,
+	"u16":       U16,
+	"u32":       U32,
+	"u64":       U64,
+	"float":     FLOAT,
+	"f32":       F32,
+	"f64":       F64,
+	"str":       STRINGTYPE,
+	"obj":       OBJECT,
+	"enum":      ENUM,
+	"list":      LIST,
+	"map":       MAP,
+	"chan":      CHANNEL,
+	"any":       ANY,
+	"bool":      BOOLEAN,
+	"char":      CHARACTER,
+	"rune":      RUNE,
+	"set":       SET,
+}
+
+// KeywordCategory groups the keywords by the part they play in the grammar
+type KeywordCategory int
+
+const (
+	NotKeyword      KeywordCategory = iota
+	TypeKeyword                     // names a type, ie. int, str, list
+	ControlKeyword                  // changes the flow of a program, ie. if, for, return
+	OperatorKeyword                 // is used as an operator, ie. and, not, in
+	DeclKeyword                     // starts a declaration, ie. fun, var, type
+	LiteralKeyword                  // is a value on its own, ie. true, false
+)
+
+var categories = map[TokenType]KeywordCategory{
+	FUNCTION:  DeclKeyword,
+	VAR:       DeclKeyword,
+	VAL:       DeclKeyword,
+	MACRO:     DeclKeyword,
+	TYPE:      DeclKeyword,
+	PUB:       DeclKeyword,
+	INTERFACE: DeclKeyword,
+	IMPORT:    DeclKeyword,
+	TEST:      DeclKeyword,
+
+	TRUE:  LiteralKeyword,
+	FALSE: LiteralKeyword,
+
+	IF:       ControlKeyword,
+	ELSE:     ControlKeyword,
+	ELIF:     ControlKeyword,
+	RETURN:   ControlKeyword,
+	MATCH:    ControlKeyword,
+	FOR:      ControlKeyword,
+	BREAK:    ControlKeyword,
+	CONTINUE: ControlKeyword,
+	YIELD:    ControlKeyword,
+	DEFER:    ControlKeyword,
+
+	AND: OperatorKeyword,
+	OR:  OperatorKeyword,
+	NOT: OperatorKeyword,
+	IS:  OperatorKeyword,
+	IN:  OperatorKeyword,
+
+	INT:        TypeKeyword,
+	I8:         TypeKeyword,
+	I16:        TypeKeyword,
+	I32:        TypeKeyword,
+	I64:        TypeKeyword,
+	UINT:       TypeKeyword,
+	U8:         TypeKeyword,
+	U16:        TypeKeyword,
+	U32:        TypeKeyword,
+	U64:        TypeKeyword,
+	FLOAT:      TypeKeyword,
+	F32:        TypeKeyword,
+	F64:        TypeKeyword,
+	STRINGTYPE: TypeKeyword,
+	OBJECT:     TypeKeyword,
+	ENUM:       TypeKeyword,
+	LIST:       TypeKeyword,
+	MAP:        TypeKeyword,
+	SET:        TypeKeyword,
+	CHANNEL:    TypeKeyword,
+	ANY:        TypeKeyword,
+	BOOLEAN:    TypeKeyword,
+	CHARACTER:  TypeKeyword,
+	RUNE:       TypeKeyword,
 }
 
 // LookupIdent will return an identifier token if the identifier
@@ -154,3 +455,24 @@ func LookupIdent(ident string) TokenType {
 	}
 	return IDENT
 }
+
+// Category returns which kind of keyword t is, or NotKeyword for
+// everything that is not a keyword
+func Category(t TokenType) KeywordCategory {
+	return categories[t]
+}
+
+// IsTypeKeyword reports whether t is a keyword that names a type
+func IsTypeKeyword(t TokenType) bool {
+	return categories[t] == TypeKeyword
+}
+
+// IsControlKeyword reports whether t is a keyword that changes control flow
+func IsControlKeyword(t TokenType) bool {
+	return categories[t] == ControlKeyword
+}
+
+// IsOperatorKeyword reports whether t is a keyword used as an operator
+func IsOperatorKeyword(t TokenType) bool {
+	return categories[t] == OperatorKeyword
+}
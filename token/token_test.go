@@ -0,0 +1,44 @@
+package token
+
+import "testing"
+
+func TestKeywordCategories(t *testing.T) {
+	tests := []struct {
+		ident    string
+		expected KeywordCategory
+	}{
+		{"int", TypeKeyword},
+		{"u64", TypeKeyword},
+		{"str", TypeKeyword},
+		{"chan", TypeKeyword},
+		{"if", ControlKeyword},
+		{"match", ControlKeyword},
+		{"break", ControlKeyword},
+		{"defer", ControlKeyword},
+		{"and", OperatorKeyword},
+		{"not", OperatorKeyword},
+		{"is", OperatorKeyword},
+		{"in", OperatorKeyword},
+		{"fun", DeclKeyword},
+		{"interface", DeclKeyword},
+		{"true", LiteralKeyword},
+		{"foobar", NotKeyword},
+	}
+
+	for _, tt := range tests {
+		tok := LookupIdent(tt.ident)
+		if got := Category(tok); got != tt.expected {
+			t.Errorf("Category(%q) wrong. expected=%d, got=%d", tt.ident, tt.expected, got)
+		}
+	}
+
+	if !IsTypeKeyword(I32) || IsTypeKeyword(IF) {
+		t.Errorf("IsTypeKeyword gave the wrong answer")
+	}
+	if !IsControlKeyword(YIELD) || IsControlKeyword(AND) {
+		t.Errorf("IsControlKeyword gave the wrong answer")
+	}
+	if !IsOperatorKeyword(OR) || IsOperatorKeyword(INT) {
+		t.Errorf("IsOperatorKeyword gave the wrong answer")
+	}
+}